@@ -18,9 +18,17 @@ import (
 
 func main() {
 	cfg := config.MustLoad()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
 	fmt.Println(cfg)
 
-	srv, log, cleanup, err := booker.New(cfg)
+	// Создаём контекст, который автоматически отменится при получении SIGINT или SIGTERM.
+	// По нему же /readyz узнаёт, что началась остановка.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv, log, cleanup, err := booker.New(ctx, cfg)
 	if err != nil {
 		if log != nil {
 			log.Error("failed to initialize app", sl.Err(err))
@@ -30,10 +38,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Создаём контекст, который автоматически отменится при получении SIGINT или SIGTERM.
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
 	// Запускаем сервер в горутине.
 	go func() {
 		log.Info("starting server", slog.String("address", srv.Addr))
@@ -46,13 +50,19 @@ func main() {
 
 	// Ждём сигнала отмены (Ctrl+C или kill) в ctx.
 	<-ctx.Done()
+	// повторный Ctrl+C завершит процесс сразу, не дожидаясь drain
+	stop()
+
+	// /readyz уже отвечает 503; даём балансировщику время это заметить, продолжая обслуживать запросы
+	log.Info("shutdown signal received, draining", slog.Duration("delay", cfg.HTTPServer.DrainDelay))
+	time.Sleep(cfg.HTTPServer.DrainDelay)
 
 	// Когда получили сигнал — начинаем shutdown.
 	// Дадим 10 секунд на завершение активных запросов и cleanup.
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	log.Info("shutdown signal received, shutting down...")
+	log.Info("shutting down...")
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Error("server shutdown failed", sl.Err(err))
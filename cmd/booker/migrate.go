@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/migrator"
+)
+
+const migrateUsage = "usage: booker migrate up|down|status|goto N|force N"
+
+// runMigrate выполняет `booker migrate ...` над БД из storage_path и возвращает код выхода.
+//
+//	up      — применить все новые миграции
+//	down    — откатить одну последнюю
+//	status  — текущая и последняя встроенная версии
+//	goto N  — перейти к версии N (вверх или вниз; 0 — откатить всё)
+//	force N — считать версию N применённой и снять dirty (после ручного исправления схемы)
+func runMigrate(cfg *config.Config, args []string) int {
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	var target uint
+	switch args[0] {
+	case "up", "down", "status":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	case "goto", "force":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		v, err := strconv.ParseUint(args[1], 10, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n%s\n", args[1], migrateUsage)
+			return 2
+		}
+		target = uint(v)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	m, err := migrator.New(cfg.StoragePath, log)
+	if err != nil {
+		log.Error("failed to connect to database", sl.Err(err))
+		return 1
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Down()
+	case "goto":
+		err = m.Goto(target)
+	case "force":
+		err = m.Force(target)
+	}
+	if err != nil {
+		log.Error("migration failed", sl.Err(err))
+		return 1
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		log.Error("failed to read schema version", sl.Err(err))
+		return 1
+	}
+	fmt.Printf("version %d, latest %d", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Print(", dirty")
+	}
+	fmt.Println()
+	return 0
+}
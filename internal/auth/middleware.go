@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"TRYREST/internal/lib/api/problem"
+)
+
+type ctxKey struct{}
+
+// Identity — аутентифицированный пользователь текущего запроса.
+type Identity struct {
+	UserID int64
+	Role   string
+	policy *Policy
+}
+
+// Can сообщает, есть ли у роли пользователя право perm.
+func (i Identity) Can(perm Permission) bool {
+	return i.policy != nil && i.policy.Allows(i.Role, perm)
+}
+
+// FromContext возвращает пользователя, положенного в контекст Middleware.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
+
+// WithIdentity кладёт пользователя в контекст.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// Middleware пропускает только запросы с валидным access-токеном в заголовке
+// "Authorization: Bearer <token>", остальным отвечает 401.
+// Роль берётся из токена, поэтому смена роли вступает в силу после /auth/refresh.
+func Middleware(tokens *TokenManager, policy *Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthorized(w, r, "Missing bearer token")
+				return
+			}
+			claims, err := tokens.ParseAccess(token)
+			if err != nil {
+				unauthorized(w, r, "Invalid or expired access token")
+				return
+			}
+			userID, _ := claims.UserID()
+			identity := Identity{UserID: userID, Role: claims.Role, policy: policy}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="booker"`)
+	problem.Write(w, r, http.StatusUnauthorized, detail)
+}
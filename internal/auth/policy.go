@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"net/http"
+
+	"TRYREST/internal/lib/api/problem"
+)
+
+// Permission — право на действие. Соответствие ролей и прав хранится в таблице role_permissions.
+type Permission string
+
+const (
+	PermManageUsers     Permission = "users:manage"       // CRUD пользователей и смена ролей
+	PermWriteEvents     Permission = "events:write"       // создание событий и изменение своих
+	PermWriteAnyEvent   Permission = "events:write_any"   // изменение и удаление чужих событий
+	PermWriteBookings   Permission = "bookings:write"     // бронирование для себя и управление своими бронями
+	PermWriteAnyBooking Permission = "bookings:write_any" // просмотр и изменение чужих бронирований
+	PermManageWebhooks  Permission = "webhooks:manage"    // подписки на вебхуки и журнал доставок
+)
+
+// Policy — права каждой роли, загруженные из хранилища при старте.
+type Policy struct {
+	roles map[string]map[Permission]bool
+}
+
+// NewPolicy строит политику из соответствия роль -> права.
+func NewPolicy(rolePermissions map[string][]string) *Policy {
+	p := &Policy{roles: make(map[string]map[Permission]bool, len(rolePermissions))}
+	for role, perms := range rolePermissions {
+		set := make(map[Permission]bool, len(perms))
+		for _, perm := range perms {
+			set[Permission(perm)] = true
+		}
+		p.roles[role] = set
+	}
+	return p
+}
+
+// Allows сообщает, есть ли у роли право. У неизвестной роли прав нет.
+func (p *Policy) Allows(role string, perm Permission) bool {
+	return p.roles[role][perm]
+}
+
+// Require пропускает только запросы пользователей, чья роль имеет право perm, остальным отвечает 403.
+// Должен стоять после Middleware.
+func Require(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ := FromContext(r.Context())
+			if !identity.Can(perm) {
+				problem.Write(w, r, http.StatusForbidden, "Role "+identity.Role+" lacks permission "+string(perm))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
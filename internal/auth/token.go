@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"TRYREST/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken — токен не подписан нашим ключом, просрочен или другого типа.
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	typeAccess  = "access"
+	typeRefresh = "refresh"
+)
+
+// Claims — содержимое наших JWT. Subject — id пользователя.
+type Claims struct {
+	jwt.RegisteredClaims
+	TokenType string `json:"token_type"`
+	Role      string `json:"role,omitempty"`
+}
+
+// UserID возвращает id пользователя из Subject.
+func (c Claims) UserID() (int64, error) {
+	return strconv.ParseInt(c.Subject, 10, 64)
+}
+
+// TokenPair — ответ /auth/login, /auth/register и /auth/refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // время жизни access-токена в секундах
+}
+
+// TokenManager выпускает и проверяет access- и refresh-токены (HS256).
+// Для токенов разных типов используются разные ключи, поэтому refresh нельзя предъявить вместо access.
+type TokenManager struct {
+	issuer        string
+	accessSecret  []byte
+	refreshSecret []byte
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+func NewTokenManager(cfg config.Auth) (*TokenManager, error) {
+	const op = "auth.NewTokenManager"
+	if cfg.AccessSecret == "" || cfg.RefreshSecret == "" {
+		return nil, fmt.Errorf("%s: access and refresh secrets must be set", op)
+	}
+	if cfg.AccessSecret == cfg.RefreshSecret {
+		return nil, fmt.Errorf("%s: access and refresh secrets must differ", op)
+	}
+	return &TokenManager{
+		issuer:        cfg.Issuer,
+		accessSecret:  []byte(cfg.AccessSecret),
+		refreshSecret: []byte(cfg.RefreshSecret),
+		accessTTL:     cfg.AccessTTL,
+		refreshTTL:    cfg.RefreshTTL,
+	}, nil
+}
+
+// Issue выпускает новую пару токенов для пользователя с ролью role.
+func (m *TokenManager) Issue(userID int64, role string) (TokenPair, error) {
+	const op = "auth.TokenManager.Issue"
+	now := time.Now()
+
+	access, err := m.sign(userID, role, typeAccess, now, m.accessTTL, m.accessSecret)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+	refresh, err := m.sign(userID, role, typeRefresh, now, m.refreshTTL, m.refreshSecret)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(m.accessTTL.Seconds()),
+	}, nil
+}
+
+// ParseAccess проверяет access-токен.
+func (m *TokenManager) ParseAccess(token string) (Claims, error) {
+	return m.parse(token, typeAccess, m.accessSecret)
+}
+
+// ParseRefresh проверяет refresh-токен.
+func (m *TokenManager) ParseRefresh(token string) (Claims, error) {
+	return m.parse(token, typeRefresh, m.refreshSecret)
+}
+
+func (m *TokenManager) sign(userID int64, role, tokenType string, now time.Time, ttl time.Duration, secret []byte) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        hex.EncodeToString(jti),
+		},
+		TokenType: tokenType,
+		Role:      role,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+func (m *TokenManager) parse(token, tokenType string, secret []byte) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims,
+		func(*jwt.Token) (any, error) { return secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if claims.TokenType != tokenType {
+		return Claims{}, fmt.Errorf("%w: expected %s token", ErrInvalidToken, tokenType)
+	}
+	if _, err := claims.UserID(); err != nil {
+		return Claims{}, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
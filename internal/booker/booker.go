@@ -2,14 +2,34 @@ package booker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	_ "time"
 
+	"TRYREST/internal/auth"
+	"TRYREST/internal/bus"
 	"TRYREST/internal/config"
 	"TRYREST/internal/handlers"
+	"TRYREST/internal/health"
+	"TRYREST/internal/holds"
+	"TRYREST/internal/idempotency"
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/lib/logger/requestlog"
 	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/metrics"
+	"TRYREST/internal/migrator"
+	"TRYREST/internal/models"
+	"TRYREST/internal/notify"
+	"TRYREST/internal/outbox"
+	"TRYREST/internal/reminders"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/storage/memory"
 	"TRYREST/internal/storage/postgre"
+	"TRYREST/internal/stream"
+	"TRYREST/internal/tracing"
+	"TRYREST/internal/webhooks"
 
 	"log/slog"
 
@@ -19,44 +39,182 @@ import (
 
 // New собирает и возвращает подготовленный (но ещё не запущенный) HTTP-сервер,
 // инициализированный логгер, cleanup-функцию и ошибку (если что-то пошло не так).
-func New(cfg *config.Config) (*http.Server, *slog.Logger, func(ctx context.Context) error, error) {
+// shutdown отменяется, когда процесс начинает останавливаться: с этого момента /readyz отвечает 503.
+func New(shutdown context.Context, cfg *config.Config) (*http.Server, *slog.Logger, func(ctx context.Context) error, error) {
 	log := setupLogger(cfg.Env)
 	log.Info("booker initialization start", slog.String("env", cfg.Env))
 
-	storage, err := postgre.New(cfg.StoragePath, log)
+	shutdownTracing, err := tracing.Setup(shutdown, cfg.Tracing)
+	if err != nil {
+		log.Error("error setting up tracing", sl.Err(err))
+		return nil, nil, nil, err
+	}
+
+	tokens, err := auth.NewTokenManager(cfg.Auth)
+	if err != nil {
+		log.Error("error creating token manager", sl.Err(err))
+		return nil, nil, nil, err
+	}
+
+	// шина доменных событий: хранилище публикует в неё изменения, подписчики уведомляют пользователей
+	messages := bus.New()
+
+	// фоновые горутины запускаются только в конце, поэтому при ошибке сборки достаточно
+	// закрыть хранилище и трассировку
+	built := false
+	defer func() {
+		if !built {
+			if err := shutdownTracing(context.Background()); err != nil {
+				log.Error("tracing shutdown failed", sl.Err(err))
+			}
+		}
+	}()
+
+	storage, err := newStorage(shutdown, cfg, log, messages)
 	if err != nil {
 		log.Error("error creating storage", sl.Err(err))
 		return nil, nil, nil, err
 	}
+	defer func() {
+		if !built {
+			closeStorage(storage, log)
+		}
+	}()
+
+	if err := seedAdmin(shutdown, storage, cfg.Auth, log); err != nil {
+		log.Error("error creating admin", sl.Err(err))
+		return nil, nil, nil, err
+	}
 
-	h := handlers.NewHandler(storage)
+	m := metrics.New()
+	messages.Subscribe(m.Observe)
+	if db, ok := any(storage).(metrics.StatsProvider); ok {
+		m.RegisterDBStats(db)
+	}
+
+	// права ролей читаются один раз: они меняются только миграциями
+	rolePermissions, err := storage.GetRolePermissions(shutdown)
+	if err != nil {
+		log.Error("error loading role permissions", sl.Err(err))
+		return nil, nil, nil, err
+	}
+	policy := auth.NewPolicy(rolePermissions)
+
+	// живые обновления событий (SSE): получает изменения из outbox и сверяется с хранилищем
+	hub := stream.NewHub(storage, cfg.Stream, log)
+
+	h := handlers.NewHandler(metrics.InstrumentStorage(storage, m), tokens, cfg.Holds.TTL, hub, cfg.Stream, cfg.Webhooks)
+	authenticate := auth.Middleware(tokens, policy)
+	// Idempotency-Key на создании ресурсов — клиенты повторяют POST при обрывах сети
+	idempotent := idempotency.Middleware(storage, cfg.Idempotency.TTL, log)
+
+	var schema health.SchemaVersioner // у memory схемы нет
+	if v, ok := any(storage).(health.SchemaVersioner); ok {
+		schema = v
+	}
+	checker := health.New(shutdown, storage, schema, migrator.Latest(), cfg.Health.Timeout, log)
 
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(m.Middleware)
+	router.Use(requestlog.New(log)) // после tracing.Middleware: в ctx уже есть спан
+	router.Use(problem.Recoverer(log))
+	router.NotFound(problem.NotFound)
+	router.MethodNotAllowed(problem.MethodNotAllowed)
+
+	// пробы оркестратора и балансировщика — без токена
+	router.Get("/healthz", checker.Liveness)
+	router.Get("/readyz", checker.Readiness)
 
+	// без отдельного адреса /metrics открыт всем, кто видит основной — закрывайте его на прокси
+	var adminSrv *http.Server
+	if cfg.HTTPServer.AdminAddress == "" {
+		router.Method(http.MethodGet, "/metrics", m.Handler())
+	} else {
+		admin := http.NewServeMux()
+		admin.Handle("GET /metrics", m.Handler())
+		admin.HandleFunc("GET /healthz", checker.Liveness)
+		admin.HandleFunc("GET /readyz", checker.Readiness)
+		adminSrv = &http.Server{
+			Addr:        cfg.HTTPServer.AdminAddress,
+			Handler:     admin,
+			ReadTimeout: cfg.HTTPServer.Timeout,
+			// WriteTimeout не ставим: на больших ответах он обрезал бы выдачу метрик
+			IdleTimeout: cfg.HTTPServer.IdleTimeout,
+		}
+	}
+
+	// регистрация и вход — единственные маршруты без токена, кроме чтения событий
+	router.Route("/auth", func(r chi.Router) {
+		r.Post("/register", h.AuthHandler.Register)
+		r.Post("/login", h.AuthHandler.Login)
+		r.Post("/refresh", h.AuthHandler.Refresh)
+	})
+
+	// пользователями управляют только администраторы
 	router.Route("/users", func(r chi.Router) {
+		r.Use(authenticate, auth.Require(auth.PermManageUsers))
 		r.Get("/", h.UserHandler.GetAllUsers)
-		r.Post("/", h.UserHandler.CreateUser)
+		r.With(idempotent).Post("/", h.UserHandler.CreateUser)
 		r.Get("/{id}", h.UserHandler.GetUserByID)
 		r.Put("/{id}", h.UserHandler.UpdateUser)
+		r.Patch("/{id}", h.UserHandler.PatchUser)
 		r.Delete("/{id}", h.UserHandler.DeleteUser)
 	})
 
 	router.Route("/events", func(r chi.Router) {
 		r.Get("/", h.EventHandler.GetAllEvents)
-		r.Post("/", h.EventHandler.CreateEvent)
 		r.Get("/{id}", h.EventHandler.GetEventByID)
-		r.Put("/{id}", h.EventHandler.UpdateEvent)
-		r.Delete("/{id}", h.EventHandler.DeleteEvent)
+		r.Get("/{id}/stream", h.StreamHandler.StreamEvent)
+		// лист ожидания — для тех, кто может бронировать
+		r.Route("/{id}/waitlist", func(r chi.Router) {
+			r.Use(authenticate, auth.Require(auth.PermWriteBookings))
+			r.Post("/", h.WaitlistHandler.JoinWaitlist)
+			r.Get("/", h.WaitlistHandler.GetWaitlistPosition)
+			r.Delete("/", h.WaitlistHandler.LeaveWaitlist)
+		})
+		r.With(authenticate, auth.Require(auth.PermWriteBookings)).Post("/{id}/holds", h.HoldHandler.CreateHold)
+		// создают события организаторы и администраторы; владение проверяет хендлер
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, auth.Require(auth.PermWriteEvents))
+			r.With(idempotent).Post("/", h.EventHandler.CreateEvent)
+			r.Put("/{id}", h.EventHandler.UpdateEvent)
+			r.Patch("/{id}", h.EventHandler.PatchEvent)
+			r.Delete("/{id}", h.EventHandler.DeleteEvent)
+		})
 	})
 
 	router.Route("/bookings", func(r chi.Router) {
+		r.Use(authenticate, auth.Require(auth.PermWriteBookings))
 		r.Get("/", h.BookingHandler.GetAllBookings)
-		r.Post("/", h.BookingHandler.CreateBooking)
+		r.With(idempotent).Post("/", h.BookingHandler.CreateBooking)
 		r.Get("/{id}", h.BookingHandler.GetBookingById)
 		r.Put("/{id}", h.BookingHandler.UpdateBooking)
+		r.Patch("/{id}", h.BookingHandler.PatchBooking)
 		r.Delete("/{id}", h.BookingHandler.DeleteBooking)
+		r.Post("/{id}/confirm", h.BookingHandler.ConfirmBooking)
+		r.Post("/{id}/cancel", h.BookingHandler.CancelBooking)
+		r.Post("/{id}/check-in", h.BookingHandler.CheckInBooking)
+	})
+
+	router.Route("/holds", func(r chi.Router) {
+		r.Use(authenticate, auth.Require(auth.PermWriteBookings))
+		r.Get("/{id}", h.HoldHandler.GetHoldByID)
+		r.Post("/{id}/confirm", h.HoldHandler.ConfirmHold)
+		r.Delete("/{id}", h.HoldHandler.ReleaseHold)
+	})
+
+	// подписки партнёров — только администраторы
+	router.Route("/webhooks", func(r chi.Router) {
+		r.Use(authenticate, auth.Require(auth.PermManageWebhooks))
+		r.Get("/", h.WebhookHandler.GetAllWebhooks)
+		// без Idempotency-Key: сохранённый ответ содержал бы секрет подписки в открытом виде
+		r.Post("/", h.WebhookHandler.CreateWebhook)
+		r.Get("/{id}", h.WebhookHandler.GetWebhookByID)
+		r.Delete("/{id}", h.WebhookHandler.DeleteWebhook)
+		r.Post("/{id}/enable", h.WebhookHandler.EnableWebhook)
+		r.Get("/{id}/deliveries", h.WebhookHandler.GetDeliveries)
 	})
 
 	srv := &http.Server{
@@ -66,33 +224,253 @@ func New(cfg *config.Config) (*http.Server, *slog.Logger, func(ctx context.Conte
 		WriteTimeout: cfg.HTTPServer.Timeout,
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
+	// потоки SSE бесконечны: без этого Shutdown ждал бы их до своего таймаута
+	srv.RegisterOnShutdown(hub.Close)
+
+	// освобождение просроченных холдов; останавливается в cleanup до закрытия хранилища
+	reaper := holds.NewReaper(storage, cfg.Holds.ReapInterval, log)
+	// удаление истёкших ключей Idempotency-Key
+	sweeper := idempotency.NewSweeper(storage, cfg.Idempotency.SweepInterval, log)
+
+	// доставка доменных событий, записанных хранилищем в outbox в одной транзакции с изменением
+	dispatcher := outbox.NewDispatcher(storage, cfg.Outbox, log)
+	dispatcher.AddSink("log", outbox.NewLogSink(log))
+	dispatcher.AddSink("webhooks", webhooks.NewSink(storage))
+	dispatcher.AddSink("stream", hub)
+	// письма пользователям — последним sink'ом; повтор сообщения писем заново не ставит:
+	// Notifier отмечает сообщение в хранилище
+	mails, err := newMailQueue(cfg.Notifications, log)
+	if err != nil {
+		log.Error("error setting up notifications", sl.Err(err))
+		return nil, nil, nil, err
+	}
+	// напоминания перед началом событий отправляются письмами и без них не нужны
+	var scheduler *reminders.Scheduler
+	if mails != nil {
+		templates, err := notify.LoadTemplates()
+		if err != nil {
+			log.Error("error loading email templates", sl.Err(err))
+			return nil, nil, nil, err
+		}
+		notifier, err := notify.NewNotifier(storage, templates, mails, cfg.Notifications.Locale, log)
+		if err != nil {
+			log.Error("error setting up notifications", sl.Err(err))
+			return nil, nil, nil, err
+		}
+		reminderSink, err := reminders.NewSink(storage, cfg.Reminders.Offsets)
+		if err != nil {
+			log.Error("error setting up reminders", sl.Err(err))
+			return nil, nil, nil, err
+		}
+		dispatcher.AddSink("reminders", reminderSink)
+		dispatcher.AddSink("notifications", notifier)
+		scheduler = reminders.NewScheduler(storage, notifier, cfg.Reminders, log)
+	}
+
+	// отправка вебхуков подписчикам
+	webhookWorker := webhooks.NewWorker(storage, cfg.Webhooks, nil, log)
+
+	// всё собрано — дальше ошибок нет, запускаем фоновые горутины
+	built = true
+	reaper.Start()
+	sweeper.Start()
+	if mails != nil {
+		mails.Start()
+		scheduler.Start()
+	}
+	dispatcher.Start()
+	hub.Start()
+	webhookWorker.Start()
+
+	if adminSrv != nil {
+		go func() {
+			log.Info("starting admin server", slog.String("address", adminSrv.Addr))
+			if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("admin server stopped unexpectedly", sl.Err(err))
+			}
+		}()
+	}
 
 	//это функция очистки ресурсов которая использует общий интерфейс(пока до конца не разобрался)
 	cleanup := func(ctx context.Context) error {
-		type closer interface {
-			Close() error
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(ctx); err != nil {
+				log.Error("admin server shutdown failed", sl.Err(err))
+			}
 		}
-		if c, ok := any(storage).(closer); ok {
-			if err := c.Close(); err != nil {
-				log.Error("storage close failed", sl.Err(err))
-				return err
+		if err := reaper.Stop(ctx); err != nil {
+			log.Error("hold reaper stop failed", sl.Err(err))
+		}
+		if err := sweeper.Stop(ctx); err != nil {
+			log.Error("idempotency key sweeper stop failed", sl.Err(err))
+		}
+		if err := dispatcher.Stop(ctx); err != nil {
+			log.Error("outbox dispatcher stop failed", sl.Err(err))
+		}
+		if scheduler != nil {
+			if err := scheduler.Stop(ctx); err != nil {
+				log.Error("reminder scheduler stop failed", sl.Err(err))
 			}
 		}
-		return nil
+		// новых писем после остановки диспетчера и планировщика не будет — отправляем накопленные
+		if mails != nil {
+			if err := mails.Stop(ctx); err != nil {
+				log.Error("notification queue stop failed", sl.Err(err))
+			}
+		}
+		if err := webhookWorker.Stop(ctx); err != nil {
+			log.Error("webhook worker stop failed", sl.Err(err))
+		}
+		if err := hub.Stop(ctx); err != nil {
+			log.Error("stream hub stop failed", sl.Err(err))
+		}
+		// после остановки сервера и реапера новых спанов уже не будет — дописываем накопленные
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("tracing shutdown failed", sl.Err(err))
+		}
+		return closeStorage(storage, log)
 	}
 
 	return srv, log, cleanup, nil
 }
 
+// newStorage выбирает бэкенд хранилища по cfg.Storage. Для postgres при database.auto_migrate
+// сначала применяет миграции, а затем отказывается стартовать, если схема отстаёт от бинарника.
+func newStorage(ctx context.Context, cfg *config.Config, log *slog.Logger, publisher bus.Publisher) (storage.Storage, error) {
+	switch cfg.Storage {
+	case "postgres", "":
+		if cfg.Database.AutoMigrate {
+			if err := migrateUp(cfg.StoragePath, log); err != nil {
+				return nil, err
+			}
+		}
+		s, err := postgre.New(cfg.StoragePath, cfg.Database.QueryTimeout, log, publisher)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSchema(ctx, s); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	case "memory":
+		log.Warn("using in-memory storage, data will be lost on exit")
+		return memory.New(log, publisher), nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
+
+// seedAdmin создаёт администратора из cfg.AdminEmail, если пользователя с таким email нет.
+// Существующему аккаунту роль не выдаётся: email мог занять кто угодно через открытую регистрацию.
+func seedAdmin(ctx context.Context, s storage.UserRepository, cfg config.Auth, log *slog.Logger) error {
+	if cfg.AdminEmail == "" {
+		return nil
+	}
+	user, err := s.GetUserByEmail(ctx, cfg.AdminEmail)
+	if err == nil {
+		if user.Role != models.RoleAdmin {
+			log.Warn("admin email belongs to a non-admin account, admin role is not granted",
+				slog.Int64("user_id", user.ID))
+		}
+		return nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if cfg.AdminPassword == "" {
+		return errors.New("auth.admin_password is required to create the admin")
+	}
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	id, err := s.AddUser(ctx, models.User{Name: "Administrator", Email: cfg.AdminEmail, PasswordHash: hash, Role: models.RoleAdmin})
+	if errors.Is(err, storage.ErrConflict) {
+		return nil // создал другой экземпляр
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("admin created", slog.Int64("user_id", id))
+	return nil
+}
+
+// closeStorage закрывает хранилище, если у бэкенда есть что закрывать.
+func closeStorage(s storage.Storage, log *slog.Logger) error {
+	type closer interface {
+		Close() error
+	}
+	if c, ok := any(s).(closer); ok {
+		if err := c.Close(); err != nil {
+			log.Error("storage close failed", sl.Err(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// newMailQueue выбирает отправителя писем по cfg.Sender и создаёт очередь. nil — письма выключены.
+func newMailQueue(cfg config.Notifications, log *slog.Logger) (*notify.Queue, error) {
+	var sender notify.Sender
+	switch cfg.Sender {
+	case "smtp":
+		sender = notify.NewSMTPSender(cfg.SMTP)
+	case "file":
+		s, err := notify.NewFileSender(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		sender = s
+	case "log", "":
+		sender = notify.NewLogSender(log)
+	case "none":
+		log.Warn("email notifications disabled")
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown notifications sender %q", cfg.Sender)
+	}
+	return notify.NewQueue(sender, cfg, log), nil
+}
+
+func migrateUp(dsn string, log *slog.Logger) error {
+	m, err := migrator.New(dsn, log)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	log.Info("applying migrations", slog.Uint64("latest", uint64(migrator.Latest())))
+	return m.Up()
+}
+
+// checkSchema не даёт запуститься со схемой старше встроенных миграций: запросы к новым колонкам падали бы
+// уже под нагрузкой. Схема новее бинарника допустима — так бывает при откате приложения.
+func checkSchema(ctx context.Context, s *postgre.Storage) error {
+	version, dirty, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty: fix it manually and run `booker migrate force N`", version)
+	}
+	if latest := migrator.Latest(); version < latest {
+		return fmt.Errorf("schema version %d is behind %d: run `booker migrate up` or set database.auto_migrate", version, latest)
+	}
+	return nil
+}
+
+// setupLogger создаёт логгер по окружению. Записи, сделанные с ctx запроса, получают trace_id и span_id.
 func setupLogger(env string) *slog.Logger {
+	var handler slog.Handler
 	switch env {
 	case "local":
-		return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	case "dev":
-		return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	case "prod":
-		return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
 	default:
-		return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
 	}
+	return slog.New(tracing.LogHandler{Handler: handler})
 }
@@ -0,0 +1,102 @@
+// Package bus — внутрипроцессная шина доменных событий: хранилище публикует в неё
+// изменения после коммита, подписчики (уведомления, логирование) их получают.
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+// Типы сообщений.
+const (
+	// WaitlistPromoted — пользователь из листа ожидания получил бронирование (Payload — models.Booking).
+	WaitlistPromoted = "WaitlistPromoted"
+)
+
+// Типы доменных событий outbox (models.OutboxMessage.Type). Хранилище записывает их
+// в одной транзакции с изменением пользователя, события или бронирования.
+const (
+	UserCreated = "UserCreated"
+	UserUpdated = "UserUpdated"
+	UserDeleted = "UserDeleted"
+
+	EventCreated = "EventCreated"
+	EventUpdated = "EventUpdated"
+	// EventRescheduled — изменились начало, окончание или часовой пояс события. Пишется вслед
+	// за EventUpdated того же изменения, чтобы участников уведомляли только о смене расписания.
+	EventRescheduled = "EventRescheduled"
+	// EventDeleted — событие удалено; его бронирования удаляются каскадом без отдельных BookingDeleted.
+	EventDeleted = "EventDeleted"
+
+	// BookingCreated — новое бронирование: напрямую, из холда или из листа ожидания.
+	BookingCreated = "BookingCreated"
+	// BookingUpdated — бронирование перенесено на другое событие или пользователя.
+	BookingUpdated   = "BookingUpdated"
+	BookingConfirmed = "BookingConfirmed"
+	BookingCancelled = "BookingCancelled"
+	BookingAttended  = "BookingAttended"
+	BookingDeleted   = "BookingDeleted"
+)
+
+// WebhookTypes — типы, на которые можно подписать вебхук. События пользователей наружу не отдаются:
+// в них личные данные.
+var WebhookTypes = []string{
+	EventCreated, EventUpdated, EventDeleted,
+	BookingCreated, BookingUpdated, BookingConfirmed, BookingCancelled, BookingAttended, BookingDeleted,
+}
+
+// Типы агрегатов (AggregateType).
+const (
+	AggregateUser    = "user"
+	AggregateEvent   = "event"
+	AggregateBooking = "booking"
+)
+
+// Message — доменное событие: что произошло (Type) и с какой сущностью.
+type Message struct {
+	Type          string
+	AggregateType string // "booking", "event", "user"
+	AggregateID   int64
+	Payload       any
+	OccurredAt    time.Time
+}
+
+// Publisher — то, куда хранилище отправляет сообщения.
+type Publisher interface {
+	Publish(msg Message)
+}
+
+// Handler обрабатывает сообщение. Вызывается синхронно в горутине публикующего,
+// поэтому долгую работу обработчик должен уносить в свою горутину или очередь.
+type Handler func(msg Message)
+
+// Bus рассылает каждое сообщение всем подписчикам. Безопасна для конкурентного использования.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+var _ Publisher = (*Bus)(nil)
+
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe добавляет подписчика на все сообщения.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *Bus) Publish(msg Message) {
+	if msg.OccurredAt.IsZero() {
+		msg.OccurredAt = time.Now()
+	}
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -10,14 +11,203 @@ import (
 
 type Config struct {
 	Env         string `yaml:"env" default:"local"`
-	StoragePath string `yaml:"storage_path" default:"./data/storage.db" required:"true"`
+	Storage     string `yaml:"storage" env:"STORAGE" env-default:"postgres"` // postgres, memory
+	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH" default:"./data/storage.db" required:"true"`
 	HTTPServer  `yaml:"http_server"`
+	Database    Database    `yaml:"database"`
+	Auth        Auth        `yaml:"auth"`
+	Holds       Holds       `yaml:"holds"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Health      Health      `yaml:"health"`
+	Tracing     Tracing     `yaml:"tracing"`
+	Outbox      Outbox      `yaml:"outbox"`
+	Webhooks    Webhooks    `yaml:"webhooks"`
+	Stream      Stream      `yaml:"stream"`
+	// Notifications — письма пользователям о бронированиях
+	Notifications Notifications `yaml:"notifications"`
+	Reminders     Reminders     `yaml:"reminders"`
 }
 
 type HTTPServer struct {
-	Address     string        `yaml:"address" default:"localhost:8080"`
+	Address     string        `yaml:"address" env:"HTTP_SERVER_ADDRESS" default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" default:"60s"`
+	// AdminAddress — отдельный адрес для /metrics, недоступный снаружи. Пустой — /metrics на основном адресе
+	AdminAddress string `yaml:"admin_address" env:"HTTP_SERVER_ADMIN_ADDRESS"`
+	// DrainDelay — сколько после сигнала остановки /readyz отвечает 503 до srv.Shutdown,
+	// чтобы балансировщик успел убрать инстанс и не получил отказов в соединении
+	DrainDelay time.Duration `yaml:"drain_delay" env-default:"5s"`
+}
+
+// Database — работа с PostgreSQL.
+type Database struct {
+	// QueryTimeout — предел на одну операцию хранилища (запрос или транзакцию). Должен быть меньше
+	// http_server.timeout, чтобы клиент получил 504, а не оборванное соединение
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DATABASE_QUERY_TIMEOUT" env-default:"3s"`
+	// AutoMigrate — применять встроенные миграции при старте, как `booker migrate up`.
+	// Без него сервер не стартует, если схема отстаёт от бинарника
+	AutoMigrate bool `yaml:"auto_migrate" env:"DATABASE_AUTO_MIGRATE"`
+}
+
+// Auth — параметры выдачи JWT. Секреты лучше передавать через переменные окружения.
+type Auth struct {
+	Issuer        string        `yaml:"issuer" env-default:"booker"`
+	AccessSecret  string        `yaml:"access_secret" env:"AUTH_ACCESS_SECRET" env-required:"true"`
+	RefreshSecret string        `yaml:"refresh_secret" env:"AUTH_REFRESH_SECRET" env-required:"true"`
+	AccessTTL     time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL    time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	// AdminEmail и AdminPassword — первый администратор, которого сервер создаёт при старте,
+	// если пользователя с таким email нет; остальным роли назначает он. Пустой AdminEmail — не создавать
+	AdminEmail    string `yaml:"admin_email" env:"AUTH_ADMIN_EMAIL"`
+	AdminPassword string `yaml:"admin_password" env:"AUTH_ADMIN_PASSWORD"`
+}
+
+// String скрывает секреты, чтобы конфиг можно было печатать и логировать.
+func (a Auth) String() string {
+	return fmt.Sprintf("{%s *** *** %s %s %s}", a.Issuer, a.AccessTTL, a.RefreshTTL, a.AdminEmail)
+}
+
+// Holds — временное удержание мест (POST /events/{id}/holds).
+type Holds struct {
+	TTL time.Duration `yaml:"ttl" env-default:"10m"`
+	// ReapInterval — как часто фоновая горутина освобождает просроченные холды
+	ReapInterval time.Duration `yaml:"reap_interval" env-default:"30s"`
+}
+
+// Idempotency — хранение ответов на POST-запросы с заголовком Idempotency-Key.
+type Idempotency struct {
+	// TTL — сколько хранится ответ; повтор ключа после этого выполняется как новый запрос
+	TTL time.Duration `yaml:"ttl" env-default:"24h"`
+	// SweepInterval — как часто удалять истёкшие ключи всех пользователей; 0 — не удалять
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"10m"`
+}
+
+// Health — проверки /healthz и /readyz.
+type Health struct {
+	// Timeout — предел на проверки зависимостей в /readyz (ping БД, версия схемы)
+	Timeout time.Duration `yaml:"timeout" env-default:"1s"`
+}
+
+// Tracing — трассировка OpenTelemetry.
+type Tracing struct {
+	// Exporter — куда отправлять спаны: none, stdout или otlp
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	// Endpoint — URL коллектора OTLP/HTTP; схема http отключает TLS
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT" env-default:"http://localhost:4318"`
+	// SampleRatio — доля записываемых трасс (0..1), если вызывающий сервис не решил за нас через traceparent
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+// Outbox — доставка доменных событий из таблицы outbox.
+type Outbox struct {
+	// PollInterval — как часто диспетчер проверяет outbox; 0 выключает доставку
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" env-default:"1s"`
+	// BatchSize — сколько сообщений забирается за один запрос
+	BatchSize int `yaml:"batch_size" env-default:"100"`
+	// Lease — на сколько забранные сообщения скрыты от других инстансов; упавший инстанс
+	// отдаёт их по истечении. Должен быть больше времени доставки пачки
+	Lease time.Duration `yaml:"lease" env-default:"30s"`
+	// MinBackoff и MaxBackoff — пределы экспоненциальной задержки между повторами
+	MinBackoff time.Duration `yaml:"min_backoff" env-default:"1s"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"10m"`
+	// MaxAttempts — после стольких неудачных попыток сообщение помечается failed и больше не задерживает
+	// следующие сообщения своего агрегата; 0 — повторять без ограничения
+	MaxAttempts int `yaml:"max_attempts" env-default:"20"`
+}
+
+// Webhooks — отправка вебхуков подписчикам.
+type Webhooks struct {
+	// PollInterval — как часто воркер ищет наступившие доставки; 0 выключает отправку
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"50"`
+	// Concurrency — сколько запросов отправляется одновременно
+	Concurrency int `yaml:"concurrency" env-default:"8"`
+	// Timeout — предел на один запрос к подписчику
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+	// Lease — на сколько забранные доставки скрыты от других инстансов; должен быть больше timeout
+	Lease time.Duration `yaml:"lease" env-default:"1m"`
+	// MinBackoff и MaxBackoff — пределы экспоненциальной задержки между повторами
+	MinBackoff time.Duration `yaml:"min_backoff" env-default:"10s"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"1h"`
+	// MaxAttempts — после стольких неудачных попыток доставка помечается failed
+	MaxAttempts int `yaml:"max_attempts" env-default:"10"`
+	// MaxFailures — после стольких неудачных попыток подряд (по всем доставкам) подписка выключается;
+	// включить её снова — POST /webhooks/{id}/enable. 0 — не выключать
+	MaxFailures int `yaml:"max_failures" env-default:"50"`
+	// AllowPrivate разрешает подписки на loopback, частные и link-local адреса — для локальной разработки.
+	// Иначе такие адреса отклоняются при создании подписки и при каждом соединении
+	AllowPrivate bool `yaml:"allow_private" env:"WEBHOOKS_ALLOW_PRIVATE" env-default:"false"`
+}
+
+// Stream — живые обновления событий через Server-Sent Events (GET /events/{id}/stream).
+type Stream struct {
+	// Heartbeat — как часто в тихий поток пишется комментарий, чтобы прокси не закрыли соединение
+	Heartbeat time.Duration `yaml:"heartbeat" env-default:"15s"`
+	// WriteTimeout — предел на запись одного сообщения; http_server.timeout к потокам не применяется.
+	// Клиент, который не читает дольше, отключается
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"10s"`
+	// ResyncInterval — как часто события с подписчиками перечитываются из хранилища: так видны холды,
+	// начало события и изменения, доставленные outbox другого инстанса. 0 — только outbox этого инстанса
+	ResyncInterval time.Duration `yaml:"resync_interval" env-default:"5s"`
+	// Buffer — очередь сообщений одного подписчика; переполнивший её отключается и переподключается
+	Buffer int `yaml:"buffer" env-default:"16"`
+	// History — сколько последних сообщений события хранится для возобновления по Last-Event-ID
+	History int `yaml:"history" env-default:"64"`
+	// MaxSubscribers — предел одновременных потоков на инстанс, сверх него — 503. 0 — без предела
+	MaxSubscribers int `yaml:"max_subscribers" env:"STREAM_MAX_SUBSCRIBERS" env-default:"10000"`
+}
+
+// Notifications — письма о создании и отмене бронирований и изменении событий.
+type Notifications struct {
+	// Sender — куда уходят письма: smtp, file (.eml-файлы в dir), log или none (не отправлять)
+	Sender string `yaml:"sender" env:"NOTIFICATIONS_SENDER" env-default:"log"`
+	// From — адрес отправителя, можно с именем: "Booker <noreply@example.com>"
+	From string `yaml:"from" env:"NOTIFICATIONS_FROM" env-default:"Booker <noreply@booker.local>"`
+	// Locale — язык писем: ru или en
+	Locale string `yaml:"locale" env:"NOTIFICATIONS_LOCALE" env-default:"ru"`
+	// Dir — каталог для sender: file
+	Dir string `yaml:"dir" env-default:"./data/mail"`
+	// QueueSize — сколько писем ждёт отправки; при переполнении outbox повторит сообщение позже
+	QueueSize int `yaml:"queue_size" env-default:"1000"`
+	// Workers — сколько писем отправляется одновременно
+	Workers int `yaml:"workers" env-default:"2"`
+	// MaxAttempts — после стольких неудачных попыток письмо отбрасывается
+	MaxAttempts int `yaml:"max_attempts" env-default:"5"`
+	// MinBackoff и MaxBackoff — пределы экспоненциальной задержки между повторами
+	MinBackoff time.Duration `yaml:"min_backoff" env-default:"5s"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"5m"`
+	SMTP       SMTP          `yaml:"smtp"`
+}
+
+// SMTP — почтовый сервер для notifications.sender: smtp. Пароль лучше передавать через SMTP_PASSWORD.
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST" env-default:"localhost"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	// ImplicitTLS — TLS с первого байта (обычно порт 465); иначе STARTTLS, если сервер его предлагает
+	ImplicitTLS bool `yaml:"implicit_tls" env:"SMTP_IMPLICIT_TLS"`
+	// Timeout — предел на отправку одного письма, включая соединение
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// String скрывает пароль, чтобы конфиг можно было печатать и логировать.
+func (s SMTP) String() string {
+	return fmt.Sprintf("{%s %d %s *** %t %s}", s.Host, s.Port, s.Username, s.ImplicitTLS, s.Timeout)
+}
+
+// Reminders — напоминания участникам перед началом события. Отправляются письмами,
+// поэтому при notifications.sender: none выключены.
+type Reminders struct {
+	// Offsets — за сколько до начала события напоминать, с точностью до секунды. Бронирование, созданное
+	// позже срока напоминания, его не получает
+	Offsets []time.Duration `yaml:"offsets" env:"REMINDERS_OFFSETS" env-default:"24h,1h"`
+	// PollInterval — как часто планировщик ищет наступившие напоминания; 0 выключает отправку
+	PollInterval time.Duration `yaml:"poll_interval" env:"REMINDERS_POLL_INTERVAL" env-default:"30s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	// Lease — на сколько забранные напоминания скрыты от других инстансов; упавший инстанс
+	// отдаёт их по истечении
+	Lease time.Duration `yaml:"lease" env-default:"1m"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"TRYREST/internal/auth"
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+type AuthHandler struct {
+	storage storage.UserRepository
+	tokens  *auth.TokenManager
+}
+
+func NewAuthHandler(storage storage.UserRepository, tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{storage: storage, tokens: tokens}
+}
+
+// authResponse — пользователь и выданные ему токены.
+type authResponse struct {
+	User models.User `json:"user"`
+	auth.TokenPair
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Registration
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validate(w, r, req) {
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to register user")
+		return
+	}
+	// при самостоятельной регистрации роль всегда attendee: администратора создаёт сервер при старте
+	user := models.User{Name: req.Name, Email: req.Email, PasswordHash: hash, Role: models.RoleAttendee}
+	user.ID, err = h.storage.AddUser(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			problem.Write(w, r, http.StatusConflict, "Email is already registered")
+			return
+		}
+		writeStorageError(w, r, err, "User", "Failed to register user")
+		return
+	}
+
+	tokens, err := h.tokens.Issue(user.ID, user.Role)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to issue tokens")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(authResponse{User: user, TokenPair: tokens})
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Credentials
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validate(w, r, req) {
+		return
+	}
+
+	user, err := h.storage.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeStorageError(w, r, err, "User", "Failed to log in")
+		return
+	}
+	// для несуществующего пользователя hash пустой, CheckPassword всё равно потратит время на bcrypt
+	if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	tokens, err := h.tokens.Issue(user.ID, user.Role)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to issue tokens")
+		return
+	}
+	json.NewEncoder(w).Encode(authResponse{User: user, TokenPair: tokens})
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.RefreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validate(w, r, req) {
+		return
+	}
+
+	claims, err := h.tokens.ParseRefresh(req.RefreshToken)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	userID, _ := claims.UserID()
+	// пользователь мог быть удалён, пока жил refresh-токен
+	user, err := h.storage.GetUserByID(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		problem.Write(w, r, http.StatusUnauthorized, "User no longer exists")
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to refresh tokens")
+		return
+	}
+
+	tokens, err := h.tokens.Issue(user.ID, user.Role)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to issue tokens")
+		return
+	}
+	json.NewEncoder(w).Encode(authResponse{User: user, TokenPair: tokens})
+}
@@ -2,31 +2,69 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"TRYREST/internal/auth"
+	"TRYREST/internal/lib/api/problem"
 	"TRYREST/internal/models"
-	"TRYREST/internal/storage/postgre"
+	"TRYREST/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type BookingHandler struct {
-	storage *postgre.Storage
+	storage storage.BookingRepository
+	// events нужны, чтобы проверить, что отметку о посещении ставит организатор события
+	events storage.EventRepository
 }
 
-func NewBookingHandler(storage *postgre.Storage) *BookingHandler {
-	return &BookingHandler{storage: storage}
+func NewBookingHandler(storage storage.BookingRepository, events storage.EventRepository) *BookingHandler {
+	return &BookingHandler{storage: storage, events: events}
 }
 
 func (h *BookingHandler) GetAllBookings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	bookings, err := h.storage.GetAllBookings()
+	page, err := parsePage(r, storage.BookingSortFields)
 	if err != nil {
-		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	json.NewEncoder(w).Encode(bookings)
+
+	filter := storage.BookingFilter{Page: page}
+	if filter.UserID, err = queryID(r, "user_id"); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	// пользователь видит только свои бронирования, администратор — любые
+	if !can(r, auth.PermWriteAnyBooking) {
+		me := currentUserID(r)
+		if filter.UserID != 0 && filter.UserID != me {
+			problem.Write(w, r, http.StatusForbidden, "Cannot list bookings of another user")
+			return
+		}
+		filter.UserID = me
+	}
+	if filter.EventID, err = queryID(r, "event_id"); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Status = r.URL.Query().Get("status")
+	if filter.Status != "" && !slices.Contains(models.BookingStatuses, filter.Status) {
+		problem.Write(w, r, http.StatusBadRequest, "query parameter status must be one of "+strings.Join(models.BookingStatuses, ", "))
+		return
+	}
+
+	filter.Limit++ // лишняя запись — признак следующей страницы
+	bookings, err := h.storage.GetAllBookings(r.Context(), filter)
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch bookings")
+		return
+	}
+	writePage(w, r, bookings, page, storage.BookingKey)
 }
 
 func (h *BookingHandler) GetBookingById(w http.ResponseWriter, r *http.Request) {
@@ -34,13 +72,15 @@ func (h *BookingHandler) GetBookingById(w http.ResponseWriter, r *http.Request)
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid booking ID")
 		return
 	}
 
-	booking, err := h.storage.GetBookingByID(id)
-	if err != nil {
-		http.Error(w, "Booking not found", http.StatusNotFound)
+	booking, ok := h.ownBooking(w, r, id)
+	if !ok {
+		return
+	}
+	if notModified(w, r, etag(booking.Version)) {
 		return
 	}
 	json.NewEncoder(w).Encode(booking)
@@ -49,19 +89,30 @@ func (h *BookingHandler) GetBookingById(w http.ResponseWriter, r *http.Request)
 func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var newBooking models.Booking
-	if err := json.NewDecoder(r.Body).Decode(&newBooking); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &newBooking) {
+		return
+	}
+	if !bookForSelf(w, r, &newBooking) {
+		return
+	}
+	if !validate(w, r, newBooking) {
 		return
 	}
 
-	id, err := h.storage.AddBooking(newBooking.EventID, newBooking.UserID)
+	id, err := h.storage.AddBooking(r.Context(), newBooking.EventID, newBooking.UserID)
 	if err != nil {
-		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+		writeStorageError(w, r, err, "Booking", "Failed to create booking")
+		return
+	}
+
+	// перечитываем, чтобы вернуть статус и created_at
+	booking, err := h.storage.GetBookingByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch booking")
 		return
 	}
-	newBooking.ID = id
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(newBooking)
+	json.NewEncoder(w).Encode(booking)
 }
 
 func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
@@ -69,27 +120,41 @@ func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
 		return
 	}
 
 	var updatedBooking models.Booking
-	if err := json.NewDecoder(r.Body).Decode(&updatedBooking); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &updatedBooking) {
+		return
+	}
+	if !bookForSelf(w, r, &updatedBooking) {
+		return
+	}
+	if !validate(w, r, updatedBooking) {
+		return
+	}
+	if _, ok := h.ownBooking(w, r, id); !ok {
 		return
 	}
 
-	if err := h.storage.UpdateBooking(id, updatedBooking.EventID, updatedBooking.UserID); err != nil {
-		if err.Error() == "storage.postgre.UpdateBooking: booking not found" {
-			http.Error(w, "Booking not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to update booking", http.StatusInternalServerError)
+	if err := h.storage.UpdateBooking(r.Context(), id, updatedBooking.EventID, updatedBooking.UserID, version); err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to update booking")
 		return
 	}
-	updatedBooking.ID = id
+
+	booking, err := h.storage.GetBookingByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch booking")
+		return
+	}
+	w.Header().Set("ETag", etag(booking.Version))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedBooking)
+	json.NewEncoder(w).Encode(booking)
 }
 
 func (h *BookingHandler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
@@ -97,17 +162,170 @@ func (h *BookingHandler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.ownBooking(w, r, id); !ok {
+		return
+	}
+	if err := h.storage.DeleteBooking(r.Context(), id, version); err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to delete booking")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PatchBooking — PATCH /bookings/{id}: JSON Merge Patch для event_id и user_id.
+// Статус через патч не меняется — для этого есть /confirm, /cancel и /check-in.
+func (h *BookingHandler) PatchBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, ok := h.ownBooking(w, r, id)
+	if !ok {
+		return
+	}
+	var merged models.Booking
+	if !mergePatch(w, r, current, &merged) {
+		return
+	}
+	if !bookForSelf(w, r, &merged) {
+		return
+	}
+	if !validate(w, r, merged) {
+		return
+	}
+
+	var patch storage.BookingPatch
+	if merged.EventID != current.EventID {
+		patch.EventID = &merged.EventID
+	}
+	if merged.UserID != current.UserID {
+		patch.UserID = &merged.UserID
+	}
+	if patch != (storage.BookingPatch{}) {
+		err = h.storage.PatchBooking(r.Context(), id, version, patch)
+	} else if version != 0 && version != current.Version {
+		err = storage.ErrVersionMismatch // менять нечего, но клиент видел устаревшую версию
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to update booking")
+		return
+	}
+
+	booking, err := h.storage.GetBookingByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch booking")
+		return
+	}
+	w.Header().Set("ETag", etag(booking.Version))
+	json.NewEncoder(w).Encode(booking)
+}
+
+// ConfirmBooking — POST /bookings/{id}/confirm: pending -> confirmed.
+func (h *BookingHandler) ConfirmBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.BookingConfirmed)
+}
+
+// CancelBooking — POST /bookings/{id}/cancel: отменённое бронирование освобождает место, но не удаляется.
+func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.BookingCancelled)
+}
+
+// CheckInBooking — POST /bookings/{id}/check-in: confirmed -> attended.
+// Отмечать посещение может только организатор события или администратор.
+func (h *BookingHandler) CheckInBooking(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.BookingAttended)
+}
+
+func (h *BookingHandler) changeStatus(w http.ResponseWriter, r *http.Request, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid booking ID")
 		return
 	}
 
-	if err := h.storage.DeleteBooking(id); err != nil {
-		if err.Error() == "storage.postgre.DeleteBooking: booking not found" {
-			http.Error(w, "Booking not found", http.StatusNotFound)
+	if status == models.BookingAttended {
+		if !h.organizesBooking(w, r, id) {
 			return
 		}
-		http.Error(w, "Failed to delete booking", http.StatusInternalServerError)
+	} else if _, ok := h.ownBooking(w, r, id); !ok {
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+
+	booking, err := h.storage.SetBookingStatus(r.Context(), id, status)
+	if errors.Is(err, storage.ErrInvalidStatus) {
+		problem.Write(w, r, http.StatusConflict, "Booking cannot become "+status+" from its current status")
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to change booking status")
+		return
+	}
+	w.Header().Set("ETag", etag(booking.Version))
+	json.NewEncoder(w).Encode(booking)
+}
+
+// organizesBooking проверяет, что текущий пользователь — организатор события бронирования
+// или может менять любые события.
+func (h *BookingHandler) organizesBooking(w http.ResponseWriter, r *http.Request, id int64) bool {
+	booking, err := h.storage.GetBookingByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch booking")
+		return false
+	}
+	event, err := h.events.GetEventByID(r.Context(), booking.EventID)
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return false
+	}
+	if event.OrganizerID != currentUserID(r) && !can(r, auth.PermWriteAnyEvent) {
+		problem.Write(w, r, http.StatusForbidden, "Only the event organizer can check in attendees")
+		return false
+	}
+	return true
+}
+
+// ownBooking загружает бронирование и проверяет, что оно принадлежит текущему пользователю
+// (администратору доступны любые). Чужие бронирования выглядят как несуществующие, чтобы не раскрывать их id.
+func (h *BookingHandler) ownBooking(w http.ResponseWriter, r *http.Request, id int64) (models.Booking, bool) {
+	booking, err := h.storage.GetBookingByID(r.Context(), id)
+	if err == nil && booking.UserID != currentUserID(r) && !can(r, auth.PermWriteAnyBooking) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Booking", "Failed to fetch booking")
+		return models.Booking{}, false
+	}
+	return booking, true
+}
+
+// bookForSelf подставляет текущего пользователя, если user_id не указан,
+// и запрещает бронировать от имени другого всем, кроме администратора.
+func bookForSelf(w http.ResponseWriter, r *http.Request, booking *models.Booking) bool {
+	me := currentUserID(r)
+	if booking.UserID == 0 {
+		booking.UserID = me
+	}
+	if booking.UserID != me && !can(r, auth.PermWriteAnyBooking) {
+		problem.Write(w, r, http.StatusForbidden, "Cannot book on behalf of another user")
+		return false
+	}
+	return true
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/models"
+)
+
+// maxBodyBytes — предельный размер тела запроса.
+const maxBodyBytes = 1 << 20
+
+// decodeJSON строго разбирает тело запроса в dst: неизвестные поля, лишние данные
+// после объекта и тело больше maxBodyBytes считаются ошибкой. При ошибке сам
+// отвечает клиенту и возвращает false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decodeStrict(w, r, http.MaxBytesReader(w, r.Body, maxBodyBytes), dst)
+}
+
+// decodeStrict — decodeJSON для произвольного источника (например, тела после merge patch).
+func decodeStrict(w http.ResponseWriter, r *http.Request, src io.Reader, dst any) bool {
+	dec := json.NewDecoder(src)
+	dec.DisallowUnknownFields()
+	// числа в any (тело merge patch) остаются json.Number: float64 теряет int64 больше 2^53
+	dec.UseNumber()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		if dec.Decode(&struct{}{}) == io.EOF {
+			return true
+		}
+		err = errors.New("body must contain a single JSON object")
+	}
+
+	var maxErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxErr):
+		problem.Write(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxErr.Limit))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		p := problem.New(http.StatusBadRequest, "Invalid request payload")
+		p.Errors = []problem.FieldError{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}
+		p.Write(w, r)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		problem.Write(w, r, http.StatusBadRequest, "Request body must be valid JSON")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// у encoding/json нет отдельного типа для этой ошибки
+		p := problem.New(http.StatusBadRequest, "Invalid request payload")
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		p.Errors = []problem.FieldError{{Field: field, Message: "is not allowed"}}
+		p.Write(w, r)
+	default:
+		problem.Write(w, r, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+	}
+	return false
+}
+
+// validate вызывает Validate и при ошибке отвечает 422 со списком невалидных полей.
+func validate(w http.ResponseWriter, r *http.Request, v interface{ Validate() error }) bool {
+	err := v.Validate()
+	if err == nil {
+		return true
+	}
+	var verr *models.ValidationError
+	if !errors.As(err, &verr) {
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+	p := problem.New(http.StatusUnprocessableEntity, "Request validation failed")
+	for _, f := range verr.Fields {
+		p.Errors = append(p.Errors, problem.FieldError{Field: f.Field, Message: f.Message})
+	}
+	p.Write(w, r)
+	return false
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TRYREST/internal/lib/api/problem"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type payload struct {
+		Name     string `json:"name"`
+		Capacity int    `json:"capacity"`
+	}
+	tests := []struct {
+		name   string
+		body   string
+		status int    // 0 — тело принято
+		field  string // поле в problem.Errors
+	}{
+		{"valid", `{"name":"Go meetup","capacity":10}`, 0, ""},
+		{"trailing whitespace", "{\"name\":\"Go meetup\"}\n\t ", 0, ""},
+		{"unknown field", `{"name":"Go meetup","organizer_id":1}`, http.StatusBadRequest, "organizer_id"},
+		{"wrong type", `{"name":"Go meetup","capacity":"ten"}`, http.StatusBadRequest, "capacity"},
+		{"second object", `{"name":"a"}{"name":"b"}`, http.StatusBadRequest, ""},
+		{"trailing garbage", `{"name":"a"} x`, http.StatusBadRequest, ""},
+		{"trailing array", `{"name":"a"}[]`, http.StatusBadRequest, ""},
+		{"empty body", ``, http.StatusBadRequest, ""},
+		{"truncated", `{"name":"a"`, http.StatusBadRequest, ""},
+		{"syntax error", `{"name":'a'}`, http.StatusBadRequest, ""},
+		{"not an object", `["a"]`, http.StatusBadRequest, ""},
+		{"too large", `{"name":"` + strings.Repeat("a", maxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			var dst payload
+			ok := decodeJSON(rec, req, &dst)
+			if ok != (tt.status == 0) {
+				t.Fatalf("decodeJSON = %v, want %v (response %d %s)", ok, tt.status == 0, rec.Code, rec.Body)
+			}
+			if ok {
+				if dst.Name != "Go meetup" {
+					t.Errorf("name = %q, want %q", dst.Name, "Go meetup")
+				}
+				return
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+			var p problem.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if tt.field != "" && (len(p.Errors) != 1 || p.Errors[0].Field != tt.field) {
+				t.Errorf("errors = %+v, want one for field %q", p.Errors, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/storage"
+)
+
+// statusClientClosedRequest — клиент закрыл соединение, не дождавшись ответа (код nginx).
+// Ответ уже никто не прочитает, но код виден в логах, метриках и трассах и не путается с 5xx.
+const statusClientClosedRequest = 499
+
+// writeStorageError — единая точка перевода ошибок хранилища в HTTP-ответ.
+// entity используется в тексте ответа ("User", "Event"...), fallback — для прочих (500) ошибок.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error, entity, fallback string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		problem.Write(w, r, http.StatusNotFound, entity+" not found")
+	case errors.Is(err, storage.ErrEventFull):
+		problem.Write(w, r, http.StatusConflict, "Event is sold out")
+	case errors.Is(err, storage.ErrEventStarted):
+		problem.Write(w, r, http.StatusConflict, "Event has already started")
+	case errors.Is(err, storage.ErrInvalidStatus):
+		problem.Write(w, r, http.StatusConflict, entity+" status does not allow this operation")
+	case errors.Is(err, storage.ErrVersionMismatch):
+		problem.Write(w, r, http.StatusPreconditionFailed, entity+" has been modified since it was read")
+	case errors.Is(err, storage.ErrConflict):
+		problem.Write(w, r, http.StatusConflict, entity+" conflicts with an existing one")
+	case errors.Is(err, storage.ErrForeignKey):
+		problem.Write(w, r, http.StatusUnprocessableEntity, "Referenced resource does not exist")
+	case errors.Is(err, context.Canceled):
+		w.WriteHeader(statusClientClosedRequest)
+	case errors.Is(err, context.DeadlineExceeded):
+		problem.Write(w, r, http.StatusGatewayTimeout, "Storage did not respond in time")
+	case errors.Is(err, storage.ErrRetryable):
+		// транзакцию откатила БД (deadlock, serialization failure), повтор безопасен
+		w.Header().Set("Retry-After", "1")
+		problem.Write(w, r, http.StatusServiceUnavailable, "Concurrent update, retry the request")
+	case errors.Is(err, storage.ErrUnavailable):
+		problem.Write(w, r, http.StatusServiceUnavailable, "Storage is temporarily unavailable")
+	default:
+		problem.Write(w, r, http.StatusInternalServerError, fallback)
+	}
+}
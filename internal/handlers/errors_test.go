@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// blockingEvents отвечает на GetEventByID, только когда ctx запроса отменён или истёк,
+// как хранилище, у которого запрос не успел выполниться.
+type blockingEvents struct {
+	storage.EventRepository
+}
+
+func (blockingEvents) GetEventByID(ctx context.Context, id int64) (models.Event, error) {
+	<-ctx.Done()
+	return models.Event{}, fmt.Errorf("storage.stub.GetEventByID: %w", ctx.Err())
+}
+
+func TestGetEventByIDContextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want int
+	}{
+		{
+			name: "client disconnected",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			want: statusClientClosedRequest,
+		},
+		{
+			name: "query timeout",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			want: http.StatusGatewayTimeout,
+		},
+	}
+
+	router := chi.NewRouter()
+	router.Get("/events/{id}", NewEventHandler(blockingEvents{}).GetEventByID)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/events/1", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStorageError(t *testing.T) {
+	tests := []struct {
+		err        error
+		want       int
+		retryAfter string
+	}{
+		{fmt.Errorf("op: %w", context.Canceled), statusClientClosedRequest, ""},
+		{fmt.Errorf("op: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, ""},
+		{fmt.Errorf("op: %w", storage.ErrRetryable), http.StatusServiceUnavailable, "1"},
+		{fmt.Errorf("op: %w", storage.ErrUnavailable), http.StatusServiceUnavailable, ""},
+		{fmt.Errorf("op: %w", storage.ErrVersionMismatch), http.StatusPreconditionFailed, ""},
+		{fmt.Errorf("op: %w", storage.ErrNotFound), http.StatusNotFound, ""},
+		{fmt.Errorf("op: unexpected"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeStorageError(rec, httptest.NewRequest(http.MethodGet, "/events/1", nil), tt.err, "Event", "Failed")
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/models"
+)
+
+// etag — сильный ETag записи с версией version: "3".
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// eventETag добавляет к версии события число свободных мест: оно меняется при бронированиях,
+// не меняя саму запись, и без него If-None-Match отдавал бы 304 с устаревшим остатком. "3-42".
+func eventETag(event models.Event) string {
+	return `"` + strconv.FormatInt(event.Version, 10) + "-" + strconv.Itoa(event.RemainingSeats) + `"`
+}
+
+// notModified выставляет ETag и, если он совпал с If-None-Match (слабое сравнение), отвечает 304.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch читает If-Match и возвращает ожидаемую версию записи для Update*/Delete* хранилища:
+// 0, если заголовка нет или он равен "*". Сравнение строгое, поэтому слабый ETag и список
+// из нескольких тегов не совпадают ни с чем — на них отвечаем 412. У событий в ETag после версии
+// идёт число мест, оно не сравнивается: бронирования не мешают редактировать событие.
+func ifMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	tag, quoted := strings.CutPrefix(header, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	tag, _, _ = strings.Cut(tag, "-")
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !quoted || !closed || strings.Contains(header, ",") || err != nil || version <= 0 {
+		problem.Write(w, r, http.StatusPreconditionFailed, "If-Match must be a single strong ETag from a previous response")
+		return 0, false
+	}
+	return version, true
+}
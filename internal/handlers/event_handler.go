@@ -2,31 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"TRYREST/internal/auth"
+	"TRYREST/internal/lib/api/problem"
 	"TRYREST/internal/models"
-	"TRYREST/internal/storage/postgre"
+	"TRYREST/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type EventHandler struct {
-	storage *postgre.Storage
+	storage storage.EventRepository
 }
 
-func NewEventHandler(storage *postgre.Storage) *EventHandler {
+func NewEventHandler(storage storage.EventRepository) *EventHandler {
 	return &EventHandler{storage: storage}
 }
 
 func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	events, err := h.storage.GetAllEvents()
+
+	page, err := parsePage(r, storage.EventSortFields)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// ?from=...&to=... в RFC 3339 — окно по времени начала события
+	filter := storage.EventFilter{Title: r.URL.Query().Get("title"), Page: page}
+	if filter.From, err = queryTime(r, "from"); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = queryTime(r, "to"); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.Limit++ // лишняя запись — признак следующей страницы
+	events, err := h.storage.GetAllEvents(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+		writeStorageError(w, r, err, "Event", "Failed to fetch events")
 		return
 	}
-	json.NewEncoder(w).Encode(events)
+	writePage(w, r, events, page, storage.EventKey)
 }
 
 func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
@@ -34,13 +56,16 @@ func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
-	event, err := h.storage.GetEventByID(id)
+	event, err := h.storage.GetEventByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return
+	}
+	if notModified(w, r, eventETag(event)) {
 		return
 	}
 	json.NewEncoder(w).Encode(event)
@@ -49,17 +74,24 @@ func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var newEvent models.Event
-	if err := json.NewDecoder(r.Body).Decode(&newEvent); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &newEvent) {
+		return
+	}
+	if newEvent.TimeZone == "" {
+		newEvent.TimeZone = "UTC"
+	}
+	if !validate(w, r, newEvent) {
 		return
 	}
+	newEvent.OrganizerID = currentUserID(r) // organizer_id из тела игнорируется
 
-	id, err := h.storage.AddEvent(newEvent.Title, newEvent.Description)
+	id, err := h.storage.AddEvent(r.Context(), newEvent)
 	if err != nil {
-		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+		writeStorageError(w, r, err, "Event", "Failed to create event")
 		return
 	}
 	newEvent.ID = id
+	newEvent.RemainingSeats = newEvent.Capacity
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(newEvent)
 }
@@ -69,27 +101,120 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
 		return
 	}
 
 	var updatedEvent models.Event
-	if err := json.NewDecoder(r.Body).Decode(&updatedEvent); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &updatedEvent) {
+		return
+	}
+	updatedEvent.ID = id
+	updatedEvent.Version = version
+	if updatedEvent.TimeZone == "" {
+		updatedEvent.TimeZone = "UTC"
+	}
+	if !validate(w, r, updatedEvent) {
+		return
+	}
+	if _, ok := h.ownEvent(w, r, id); !ok {
 		return
 	}
 
-	if err := h.storage.UpdateEvent(id, updatedEvent.Title, updatedEvent.Description); err != nil {
-		if err.Error() == "storage.postgre.UpdateEvent: event not found" {
-			http.Error(w, "Event not found", http.StatusNotFound)
+	if err := h.storage.UpdateEvent(r.Context(), updatedEvent); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			problem.Write(w, r, http.StatusConflict, "Capacity is less than the number of booked seats")
 			return
 		}
-		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		writeStorageError(w, r, err, "Event", "Failed to update event")
 		return
 	}
-	updatedEvent.ID = id
+
+	// перечитываем, чтобы вернуть актуальное remaining_seats
+	event, err := h.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return
+	}
+	w.Header().Set("ETag", eventETag(event))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedEvent)
+	json.NewEncoder(w).Encode(event)
+}
+
+// PatchEvent — PATCH /events/{id}: JSON Merge Patch, меняются только переданные поля.
+// Валидируется событие целиком, каким оно станет после патча (например, end_at позже start_at).
+func (h *EventHandler) PatchEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, ok := h.ownEvent(w, r, id)
+	if !ok {
+		return
+	}
+	var merged models.Event
+	if !mergePatch(w, r, current, &merged) {
+		return
+	}
+	if merged.TimeZone == "" {
+		merged.TimeZone = "UTC"
+	}
+	if !validate(w, r, merged) {
+		return
+	}
+
+	var patch storage.EventPatch
+	if merged.Title != current.Title {
+		patch.Title = &merged.Title
+	}
+	if merged.Description != current.Description {
+		patch.Description = &merged.Description
+	}
+	if !merged.StartAt.Equal(current.StartAt) {
+		patch.StartAt = &merged.StartAt
+	}
+	if !merged.EndAt.Equal(current.EndAt) {
+		patch.EndAt = &merged.EndAt
+	}
+	if merged.TimeZone != current.TimeZone {
+		patch.TimeZone = &merged.TimeZone
+	}
+	if merged.Capacity != current.Capacity {
+		patch.Capacity = &merged.Capacity
+	}
+	if patch != (storage.EventPatch{}) {
+		err = h.storage.PatchEvent(r.Context(), id, version, patch)
+	} else if version != 0 && version != current.Version {
+		err = storage.ErrVersionMismatch // менять нечего, но клиент видел устаревшую версию
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		problem.Write(w, r, http.StatusConflict, "Capacity is less than the number of booked seats")
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to update event")
+		return
+	}
+
+	event, err := h.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return
+	}
+	w.Header().Set("ETag", eventETag(event))
+	json.NewEncoder(w).Encode(event)
 }
 
 func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
@@ -97,17 +222,35 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.storage.DeleteEvent(id); err != nil {
-		if err.Error() == "storage.postgre.DeleteEvent: event not found" {
-			http.Error(w, "Event not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to delete event", http.StatusInternalServerError)
+	if _, ok := h.ownEvent(w, r, id); !ok {
+		return
+	}
+	if err := h.storage.DeleteEvent(r.Context(), id, version); err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to delete event")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ownEvent загружает событие и проверяет, что текущий пользователь — его организатор или может менять
+// любые события. События публичны, поэтому на чужое отвечаем 403, а не 404.
+func (h *EventHandler) ownEvent(w http.ResponseWriter, r *http.Request, id int64) (models.Event, bool) {
+	event, err := h.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return models.Event{}, false
+	}
+	if event.OrganizerID != currentUserID(r) && !can(r, auth.PermWriteAnyEvent) {
+		problem.Write(w, r, http.StatusForbidden, "Only the organizer can modify this event")
+		return models.Event{}, false
+	}
+	return event, true
+}
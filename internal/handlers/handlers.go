@@ -1,19 +1,50 @@
 package handlers
 
-import "TRYREST/internal/storage/postgre"
+import (
+	"net/http"
+	"time"
+
+	"TRYREST/internal/auth"
+	"TRYREST/internal/config"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/stream"
+)
 
 // в одно ведро собрали все хендлеры
 type Handler struct {
-	UserHandler    *UserHandler
-	EventHandler   *EventHandler
-	BookingHandler *BookingHandler
+	AuthHandler     *AuthHandler
+	UserHandler     *UserHandler
+	EventHandler    *EventHandler
+	BookingHandler  *BookingHandler
+	WaitlistHandler *WaitlistHandler
+	HoldHandler     *HoldHandler
+	WebhookHandler  *WebhookHandler
+	StreamHandler   *StreamHandler
 }
 
 // инициализирует все под-хендлеры
-func NewHandler(storage *postgre.Storage) *Handler {
+func NewHandler(storage storage.Storage, tokens *auth.TokenManager, holdTTL time.Duration, hub *stream.Hub, streamCfg config.Stream,
+	webhooksCfg config.Webhooks) *Handler {
 	return &Handler{
-		UserHandler:    NewUserHandler(storage),
-		EventHandler:   NewEventHandler(storage),
-		BookingHandler: NewBookingHandler(storage),
+		AuthHandler:     NewAuthHandler(storage, tokens),
+		UserHandler:     NewUserHandler(storage),
+		EventHandler:    NewEventHandler(storage),
+		BookingHandler:  NewBookingHandler(storage, storage),
+		WaitlistHandler: NewWaitlistHandler(storage),
+		HoldHandler:     NewHoldHandler(storage, holdTTL),
+		WebhookHandler:  NewWebhookHandler(storage, webhooksCfg.AllowPrivate),
+		StreamHandler:   NewStreamHandler(storage, hub, streamCfg),
 	}
 }
+
+// currentUserID — id пользователя, аутентифицированного auth.Middleware.
+func currentUserID(r *http.Request) int64 {
+	identity, _ := auth.FromContext(r.Context())
+	return identity.UserID
+}
+
+// can сообщает, есть ли у текущего пользователя право perm.
+func can(r *http.Request, perm auth.Permission) bool {
+	identity, _ := auth.FromContext(r.Context())
+	return identity.Can(perm)
+}
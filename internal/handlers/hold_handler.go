@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"TRYREST/internal/auth"
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HoldHandler — временное удержание места на время оформления бронирования.
+type HoldHandler struct {
+	storage storage.HoldRepository
+	ttl     time.Duration // config.Holds.TTL
+}
+
+func NewHoldHandler(storage storage.HoldRepository, ttl time.Duration) *HoldHandler {
+	return &HoldHandler{storage: storage, ttl: ttl}
+}
+
+// CreateHold — POST /events/{id}/holds: удерживает место за текущим пользователем.
+func (h *HoldHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	hold, err := h.storage.AddHold(r.Context(), eventID, currentUserID(r), h.ttl)
+	if errors.Is(err, storage.ErrConflict) {
+		problem.Write(w, r, http.StatusConflict, "A seat on this event is already held for you")
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to hold a seat")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *HoldHandler) GetHoldByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	hold, ok := h.ownHold(w, r, id)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ConfirmHold — POST /holds/{id}/confirm: превращает холд в подтверждённое бронирование.
+func (h *HoldHandler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	if _, ok := h.ownHold(w, r, id); !ok {
+		return
+	}
+	booking, err := h.storage.ConfirmHold(r.Context(), id)
+	if errors.Is(err, storage.ErrHoldExpired) {
+		problem.Write(w, r, http.StatusGone, "Hold has expired")
+		return
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Hold", "Failed to confirm hold")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(booking)
+}
+
+// ReleaseHold — DELETE /holds/{id}: досрочно отпускает место.
+func (h *HoldHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	if _, ok := h.ownHold(w, r, id); !ok {
+		return
+	}
+	if err := h.storage.ReleaseHold(r.Context(), id); err != nil {
+		writeStorageError(w, r, err, "Hold", "Failed to release hold")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownHold загружает холд и проверяет, что он принадлежит текущему пользователю
+// (администратору доступны любые). Чужие холды выглядят как несуществующие.
+func (h *HoldHandler) ownHold(w http.ResponseWriter, r *http.Request, id int64) (models.Hold, bool) {
+	hold, err := h.storage.GetHoldByID(r.Context(), id)
+	if err == nil && hold.UserID != currentUserID(r) && !can(r, auth.PermWriteAnyBooking) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "Hold", "Failed to fetch hold")
+		return models.Hold{}, false
+	}
+	return hold, true
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"TRYREST/internal/storage"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor — содержимое непрозрачного курсора: сортировка, под которую он выдан,
+// и ключ последней записи страницы.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"i"`
+}
+
+// pageResponse — тело ответа списочных эндпоинтов.
+type pageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Next — ссылка на следующую страницу с теми же фильтрами; дублируется в заголовке Link
+	Next string `json:"next,omitempty"`
+}
+
+// parsePage разбирает query-параметры limit, sort (поле или -поле) и cursor.
+// sortFields[0] — сортировка по умолчанию.
+func parsePage(r *http.Request, sortFields []string) (storage.Page, error) {
+	q := r.URL.Query()
+	page := storage.Page{Limit: defaultPageLimit, Sort: sortFields[0]}
+
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return storage.Page{}, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	sortParam := q.Get("sort")
+	if sortParam != "" {
+		field := strings.TrimPrefix(sortParam, "-")
+		if !slices.Contains(sortFields, field) {
+			return storage.Page{}, fmt.Errorf("sort must be one of %s, optionally prefixed with '-'", strings.Join(sortFields, ", "))
+		}
+		page.Sort, page.Desc = field, strings.HasPrefix(sortParam, "-")
+	}
+
+	if value := q.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil || !slices.Contains(sortFields, cursor.Sort) {
+			return storage.Page{}, errors.New("invalid cursor")
+		}
+		if sortParam == "" {
+			page.Sort, page.Desc = cursor.Sort, cursor.Desc
+		} else if cursor.Sort != page.Sort || cursor.Desc != page.Desc {
+			return storage.Page{}, errors.New("cursor was issued for a different sort order")
+		}
+		page.After = &storage.Cursor{Value: cursor.Value, ID: cursor.ID}
+	}
+	return page, nil
+}
+
+// writePage отдаёт страницу списка. items должны быть запрошены с лимитом page.Limit+1:
+// лишняя запись означает, что есть следующая страница.
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T, page storage.Page, key func(T, string) storage.Cursor) {
+	resp := pageResponse[T]{Items: items}
+	if len(items) > page.Limit {
+		resp.Items = items[:page.Limit]
+		last := key(resp.Items[len(resp.Items)-1], page.Sort)
+		resp.NextCursor = encodeCursor(pageCursor{Sort: page.Sort, Desc: page.Desc, Value: last.Value, ID: last.ID})
+
+		next := *r.URL
+		q := next.Query()
+		q.Set("cursor", resp.NextCursor)
+		next.RawQuery = q.Encode()
+		resp.Next = next.RequestURI()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, resp.Next))
+	}
+	if resp.Items == nil {
+		resp.Items = []T{}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func encodeCursor(c pageCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(raw, &c)
+	return c, err
+}
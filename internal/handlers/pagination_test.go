@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	start := time.Date(2030, 3, 1, 15, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{ID: 1, Title: "A", StartAt: start},
+		{ID: 2, Title: "B", StartAt: start.Add(time.Hour)},
+		{ID: 3, Title: "C", StartAt: start.Add(2 * time.Hour)},
+	}
+	for _, sort := range []string{"start_at", "-start_at", "title", "-id"} {
+		t.Run(sort, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/events?limit=2&title=x&sort="+sort, nil)
+			page, err := parsePage(req, storage.EventSortFields)
+			if err != nil {
+				t.Fatalf("parsePage: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			writePage(rec, req, events, page, storage.EventKey)
+
+			var resp pageResponse[models.Event]
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Items) != 2 || resp.NextCursor == "" {
+				t.Fatalf("items = %d, next cursor %q; want 2 and a cursor", len(resp.Items), resp.NextCursor)
+			}
+			if link := rec.Header().Get("Link"); link != `<`+resp.Next+`>; rel="next"` {
+				t.Errorf("Link = %q, want it to point to %q", link, resp.Next)
+			}
+
+			// следующая страница: ссылка Next сохраняет фильтры, а курсор — сортировку и ключ
+			next, err := url.Parse(resp.Next)
+			if err != nil {
+				t.Fatalf("parse next: %v", err)
+			}
+			if got := next.Query(); got.Get("title") != "x" || got.Get("limit") != "2" {
+				t.Errorf("next query = %v, want the same filters", got)
+			}
+			nextPage, err := parsePage(httptest.NewRequest(http.MethodGet, "/events?cursor="+resp.NextCursor, nil), storage.EventSortFields)
+			if err != nil {
+				t.Fatalf("parsePage(next): %v", err)
+			}
+			want := storage.EventKey(events[1], page.Sort)
+			if nextPage.Sort != page.Sort || nextPage.Desc != page.Desc || nextPage.After == nil || *nextPage.After != want {
+				t.Errorf("next page = %+v (after %+v), want sort %s desc %v after %+v",
+					nextPage, nextPage.After, page.Sort, page.Desc, want)
+			}
+		})
+	}
+}
+
+func TestLastPageHasNoCursor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events?limit=2", nil)
+	rec := httptest.NewRecorder()
+	writePage(rec, req, []models.Event{{ID: 1}, {ID: 2}}, storage.Page{Limit: 2, Sort: "id"}, storage.EventKey)
+	if rec.Header().Get("Link") != "" || strings.Contains(rec.Body.String(), "next") {
+		t.Errorf("last page: Link %q, body %s; want no next page", rec.Header().Get("Link"), rec.Body)
+	}
+}
+
+func TestParsePageRejectsBadCursors(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	valid := encodeCursor(pageCursor{Sort: "title", Value: "B", ID: 2})
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"not base64", "cursor=%21%21%21", "invalid cursor"},
+		{"padded base64", "cursor=" + base64.URLEncoding.EncodeToString([]byte(`{"s":"id","i":1}`)), "invalid cursor"},
+		{"truncated", "cursor=" + valid[:len(valid)-4], "invalid cursor"},
+		{"not json", "cursor=" + encode("title:B:2"), "invalid cursor"},
+		{"wrong types", "cursor=" + encode(`{"s":"title","v":"B","i":"2"}`), "invalid cursor"},
+		{"unknown sort field", "cursor=" + encode(`{"s":"password_hash","v":"x","i":1}`), "invalid cursor"},
+		{"injected sort field", "cursor=" + encode(`{"s":"title; DROP TABLE events","i":1}`), "invalid cursor"},
+		{"other sort order", "sort=-title&cursor=" + valid, "cursor was issued for a different sort order"},
+		{"other sort field", "sort=start_at&cursor=" + valid, "cursor was issued for a different sort order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePage(httptest.NewRequest(http.MethodGet, "/events?"+tt.query, nil), storage.EventSortFields)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("parsePage(%s) = %v, want %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePageLimitAndSort(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    storage.Page
+		wantErr bool
+	}{
+		{"", storage.Page{Limit: defaultPageLimit, Sort: "start_at"}, false},
+		{"limit=100&sort=-title", storage.Page{Limit: 100, Sort: "title", Desc: true}, false},
+		{"limit=0", storage.Page{}, true},
+		{"limit=101", storage.Page{}, true},
+		{"limit=ten", storage.Page{}, true},
+		{"sort=capacity", storage.Page{}, true},
+		{"sort=--title", storage.Page{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, err := parsePage(httptest.NewRequest(http.MethodGet, "/events?"+tt.query, nil), storage.EventSortFields)
+			if (err != nil) != tt.wantErr || page != tt.want {
+				t.Errorf("parsePage(%q) = %+v, %v; want %+v, error %v", tt.query, page, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
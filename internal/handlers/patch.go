@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"TRYREST/internal/lib/api/problem"
+)
+
+// mergePatchContentType — media type JSON Merge Patch (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
+// mergePatch применяет к current JSON Merge Patch (RFC 7396) из тела запроса и строго декодирует
+// результат в dst. null в патче удаляет поле, то есть сбрасывает его в нулевое значение, — обязательные
+// поля после этого не пройдут валидацию. Поля только для чтения (id, remaining_seats...) в патче
+// допустимы, но хранилище их не меняет. При ошибке сам отвечает клиенту и возвращает false.
+func mergePatch(w http.ResponseWriter, r *http.Request, current, dst any) bool {
+	// application/json тоже принимаем: многие клиенты не умеют выставлять другой тип
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchContentType && mediaType != "application/json" {
+		problem.Write(w, r, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+		return false
+	}
+
+	var patch map[string]any
+	if !decodeJSON(w, r, &patch) {
+		return false
+	}
+	if patch == nil {
+		problem.Write(w, r, http.StatusBadRequest, "Merge patch must be a JSON object")
+		return false
+	}
+
+	// current всегда сериализуется в объект, поэтому ошибки здесь — ошибки программы
+	raw, err := json.Marshal(current)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to apply patch")
+		return false
+	}
+	var target map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // id и прочие int64 не должны пройти через float64
+	if err := dec.Decode(&target); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to apply patch")
+		return false
+	}
+	merged, err := json.Marshal(mergeJSON(target, patch))
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to apply patch")
+		return false
+	}
+	return decodeStrict(w, r, bytes.NewReader(merged), dst)
+}
+
+// mergeJSON — алгоритм MergePatch из RFC 7396, раздел 2.
+func mergeJSON(target, patch any) any {
+	fields, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = make(map[string]any, len(fields))
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = mergeJSON(object[name], value)
+		}
+	}
+	return object
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeAny разбирает JSON так же, как mergePatch: числа остаются json.Number.
+func decodeAny(t *testing.T, raw string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return v
+}
+
+// TestMergeJSON — примеры из приложения A RFC 7396 и большие целые.
+func TestMergeJSON(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// int64 больше 2^53 не округляются ни в цели, ни в патче
+		{`{"id":9007199254740993,"n":1}`, `{"n":9223372036854775807}`, `{"id":9007199254740993,"n":9223372036854775807}`},
+		{`{"id":9007199254740993}`, `{"id":null}`, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" + "+tt.patch, func(t *testing.T) {
+			got, err := json.Marshal(mergeJSON(decodeAny(t, tt.target), decodeAny(t, tt.patch)))
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			want, _ := json.Marshal(decodeAny(t, tt.want))
+			if !bytes.Equal(got, want) {
+				t.Errorf("merge = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	type resource struct {
+		ID      int64   `json:"id"`
+		EventID int64   `json:"event_id"`
+		Title   string  `json:"title"`
+		Note    *string `json:"note,omitempty"`
+	}
+	note := "bring a laptop"
+	current := resource{ID: 9007199254740993, EventID: 1, Title: "Go meetup", Note: &note}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int // 0 — патч применён
+		want        resource
+	}{
+		{"keeps other fields", mergePatchContentType, `{"title":"Go meetup #2"}`,
+			0, resource{ID: 9007199254740993, EventID: 1, Title: "Go meetup #2", Note: &note}},
+		{"null deletes", mergePatchContentType, `{"note":null}`,
+			0, resource{ID: 9007199254740993, EventID: 1, Title: "Go meetup"}},
+		{"int64 above 2^53", "application/json; charset=utf-8", `{"event_id":9007199254740995}`,
+			0, resource{ID: 9007199254740993, EventID: 9007199254740995, Title: "Go meetup", Note: &note}},
+		{"max int64", mergePatchContentType, `{"event_id":9223372036854775807}`,
+			0, resource{ID: 9007199254740993, EventID: 9223372036854775807, Title: "Go meetup", Note: &note}},
+		{"fraction in an integer field", mergePatchContentType, `{"event_id":1.5}`, http.StatusBadRequest, resource{}},
+		{"wrong type", mergePatchContentType, `{"title":1}`, http.StatusBadRequest, resource{}},
+		{"unknown field", mergePatchContentType, `{"organizer":1}`, http.StatusBadRequest, resource{}},
+		{"not an object", mergePatchContentType, `null`, http.StatusBadRequest, resource{}},
+		{"trailing data", mergePatchContentType, `{"title":"a"} {}`, http.StatusBadRequest, resource{}},
+		{"json patch", "application/json-patch+json", `[]`, http.StatusUnsupportedMediaType, resource{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/resources/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			var got resource
+			ok := mergePatch(rec, req, current, &got)
+			if ok != (tt.status == 0) {
+				t.Fatalf("mergePatch = %v, want %v (response %d %s)", ok, tt.status == 0, rec.Code, rec.Body)
+			}
+			if !ok {
+				if rec.Code != tt.status {
+					t.Errorf("status = %d, want %d", rec.Code, tt.status)
+				}
+				return
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("merged = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// queryTime читает необязательный query-параметр в формате RFC 3339.
+// Отсутствующий параметр даёт нулевое время.
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query parameter %s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// queryID читает необязательный query-параметр с идентификатором. Отсутствующий параметр даёт 0.
+func queryID(r *http.Request, name string) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("query parameter %s must be a positive integer", name)
+	}
+	return id, nil
+}
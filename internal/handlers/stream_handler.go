@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/stream"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// StreamHandler — живые обновления события через Server-Sent Events.
+type StreamHandler struct {
+	storage storage.EventRepository
+	hub     *stream.Hub
+	cfg     config.Stream
+}
+
+func NewStreamHandler(storage storage.EventRepository, hub *stream.Hub, cfg config.Stream) *StreamHandler {
+	return &StreamHandler{storage: storage, hub: hub, cfg: cfg}
+}
+
+// StreamEvent — GET /events/{id}/stream: поток свободных мест и изменений события (text/event-stream).
+// Первым приходит текущая доступность или, при заголовке Last-Event-ID, пропущенные сообщения.
+func (h *StreamHandler) StreamEvent(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	event, err := h.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Event", "Failed to fetch event")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// http_server.timeout рассчитан на обычные ответы и оборвал бы поток: снимаем его,
+	// а зависшего клиента ловим дедлайном на каждую запись
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	sub, backlog, err := h.hub.Subscribe(event, r.Header.Get("Last-Event-ID"))
+	if errors.Is(err, stream.ErrClosed) || errors.Is(err, stream.ErrTooManySubscribers) {
+		w.Header().Set("Retry-After", "5")
+		problem.Write(w, r, http.StatusServiceUnavailable, "Stream is temporarily unavailable")
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to subscribe to event")
+		return
+	}
+	defer h.hub.Unsubscribe(sub)
+
+	write := func(chunk string) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout)); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен копить поток
+	w.WriteHeader(http.StatusOK)
+	// retry — через сколько миллисекунд EventSource переподключается после обрыва
+	if err := write("retry: 2000\n\n"); err != nil {
+		return
+	}
+	for _, msg := range backlog {
+		if err := write(formatMessage(msg)); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return // хаб закрыл поток: остановка сервера, удаление события или медленный клиент
+			}
+			if err := write(formatMessage(msg)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := write(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// formatMessage — сообщение в формате text/event-stream; JSON в data однострочный.
+func formatMessage(msg stream.Message) string {
+	return fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, msg.Data)
+}
@@ -5,27 +5,36 @@ import (
 	"net/http"
 	"strconv"
 
+	"TRYREST/internal/lib/api/problem"
 	"TRYREST/internal/models"
-	"TRYREST/internal/storage/postgre"
+	"TRYREST/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
 type UserHandler struct {
-	storage *postgre.Storage
+	storage storage.UserRepository
 }
 
-func NewUserHandler(storage *postgre.Storage) *UserHandler {
+func NewUserHandler(storage storage.UserRepository) *UserHandler {
 	return &UserHandler{storage: storage}
 }
 
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	users, err := h.storage.GetAllUsers()
+	page, err := parsePage(r, storage.UserSortFields)
 	if err != nil {
-		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	json.NewEncoder(w).Encode(users)
+
+	filter := storage.UserFilter{Email: r.URL.Query().Get("email"), Page: page}
+	filter.Limit++ // лишняя запись — признак следующей страницы
+	users, err := h.storage.GetAllUsers(r.Context(), filter)
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to fetch users")
+		return
+	}
+	writePage(w, r, users, page, storage.UserKey)
 }
 
 func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
@@ -33,13 +42,16 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	user, err := h.storage.GetUserByID(id)
+	user, err := h.storage.GetUserByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeStorageError(w, r, err, "User", "Failed to fetch user")
+		return
+	}
+	if notModified(w, r, etag(user.Version)) {
 		return
 	}
 	json.NewEncoder(w).Encode(user)
@@ -48,14 +60,19 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var newUser models.User
-	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &newUser) {
+		return
+	}
+	if !validate(w, r, newUser) {
 		return
 	}
 
-	id, err := h.storage.AddUser(newUser.Name, newUser.Email)
+	if newUser.Role == "" {
+		newUser.Role = models.RoleAttendee
+	}
+	id, err := h.storage.AddUser(r.Context(), models.User{Name: newUser.Name, Email: newUser.Email, Role: newUser.Role})
 	if err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		writeStorageError(w, r, err, "User", "Failed to create user")
 		return
 	}
 	newUser.ID = id
@@ -68,27 +85,38 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
 		return
 	}
 
 	var updatedUser models.User
-	if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &updatedUser) {
 		return
 	}
-
-	if err := h.storage.UpdateUser(id, updatedUser.Name, updatedUser.Email); err != nil {
-		if err.Error() == "storage.postgre.UpdateUser: user not found" {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+	if !validate(w, r, updatedUser) {
 		return
 	}
+
 	updatedUser.ID = id
+	updatedUser.Version = version
+	if err := h.storage.UpdateUser(r.Context(), updatedUser); err != nil {
+		writeStorageError(w, r, err, "User", "Failed to update user")
+		return
+	}
+
+	// перечитываем, чтобы вернуть роль, если она не передавалась
+	user, err := h.storage.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to fetch user")
+		return
+	}
+	w.Header().Set("ETag", etag(user.Version))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedUser)
+	json.NewEncoder(w).Encode(user)
 }
 
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
@@ -96,17 +124,75 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.storage.DeleteUser(id); err != nil {
-		if err.Error() == "storage.postgre.DeleteUser: user not found" {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+	if err := h.storage.DeleteUser(r.Context(), id, version); err != nil {
+		writeStorageError(w, r, err, "User", "Failed to delete user")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// PatchUser — PATCH /users/{id}: JSON Merge Patch, меняются только переданные поля.
+// Валидируется пользователь целиком, каким он станет после патча.
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.storage.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to fetch user")
+		return
+	}
+	var merged models.User
+	if !mergePatch(w, r, current, &merged) {
+		return
+	}
+	if !validate(w, r, merged) {
+		return
+	}
+
+	var patch storage.UserPatch
+	if merged.Name != current.Name {
+		patch.Name = &merged.Name
+	}
+	if merged.Email != current.Email {
+		patch.Email = &merged.Email
+	}
+	// пустая роль, как и в PUT, оставляет текущую
+	if merged.Role != current.Role && merged.Role != "" {
+		patch.Role = &merged.Role
+	}
+	if patch != (storage.UserPatch{}) {
+		err = h.storage.PatchUser(r.Context(), id, version, patch)
+	} else if version != 0 && version != current.Version {
+		err = storage.ErrVersionMismatch // менять нечего, но клиент видел устаревшую версию
+	}
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to update user")
+		return
+	}
+
+	user, err := h.storage.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "User", "Failed to fetch user")
+		return
+	}
+	w.Header().Set("ETag", etag(user.Version))
+	json.NewEncoder(w).Encode(user)
+}
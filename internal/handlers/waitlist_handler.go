@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WaitlistHandler — очередь текущего пользователя на распроданное событие (/events/{id}/waitlist).
+type WaitlistHandler struct {
+	storage storage.WaitlistRepository
+}
+
+func NewWaitlistHandler(storage storage.WaitlistRepository) *WaitlistHandler {
+	return &WaitlistHandler{storage: storage}
+}
+
+func (h *WaitlistHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	entry, err := h.storage.JoinWaitlist(r.Context(), eventID, currentUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrEventNotFull):
+			problem.Write(w, r, http.StatusConflict, "Event has free seats, book it instead")
+		case errors.Is(err, storage.ErrConflict):
+			problem.Write(w, r, http.StatusConflict, "Already on the waitlist or booked for this event")
+		default:
+			writeStorageError(w, r, err, "Event", "Failed to join waitlist")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *WaitlistHandler) GetWaitlistPosition(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	entry, err := h.storage.GetWaitlistEntry(r.Context(), eventID, currentUserID(r))
+	if err != nil {
+		writeStorageError(w, r, err, "Waitlist entry", "Failed to fetch waitlist position")
+		return
+	}
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *WaitlistHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	idStr := chi.URLParam(r, "id")
+	eventID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	if err := h.storage.LeaveWaitlist(r.Context(), eventID, currentUserID(r)); err != nil {
+		writeStorageError(w, r, err, "Waitlist entry", "Failed to leave waitlist")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/webhooks"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookHandler — подписки партнёров на доменные события и журнал их доставок.
+type WebhookHandler struct {
+	storage storage.WebhookRepository
+	// allowPrivate — webhooks.allow_private: не проверять, что адрес подписки публичный
+	allowPrivate bool
+}
+
+func NewWebhookHandler(storage storage.WebhookRepository, allowPrivate bool) *WebhookHandler {
+	return &WebhookHandler{storage: storage, allowPrivate: allowPrivate}
+}
+
+// CreateWebhook — POST /webhooks. Секрет возвращается только в этом ответе.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var newWebhook models.Webhook
+	if !decodeJSON(w, r, &newWebhook) {
+		return
+	}
+	if !validate(w, r, newWebhook) {
+		return
+	}
+	if !h.allowPrivate && !checkTarget(w, r, newWebhook.URL) {
+		return
+	}
+
+	webhook, err := h.storage.AddWebhook(r.Context(), models.Webhook{
+		URL:        newWebhook.URL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(newWebhook.EventTypes))),
+		Secret:     newWebhook.Secret,
+	})
+	if err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to create webhook")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// checkTarget отклоняет подписку на адрес во внутренней сети (422) и на имя, которое не разрешается.
+func checkTarget(w http.ResponseWriter, r *http.Request, rawURL string) bool {
+	err := webhooks.CheckTarget(r.Context(), rawURL)
+	if err == nil {
+		return true
+	}
+	message := "host cannot be resolved"
+	if errors.Is(err, webhooks.ErrForbiddenTarget) {
+		message = "must not point to a loopback, private or link-local address"
+	}
+	p := problem.New(http.StatusUnprocessableEntity, "Request validation failed")
+	p.Errors = append(p.Errors, problem.FieldError{Field: "url", Message: message})
+	p.Write(w, r)
+	return false
+}
+
+func (h *WebhookHandler) GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	webhooks, err := h.storage.GetAllWebhooks(r.Context())
+	if err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to fetch webhooks")
+		return
+	}
+	resp := pageResponse[models.Webhook]{Items: make([]models.Webhook, 0, len(webhooks))}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+		resp.Items = append(resp.Items, webhook)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookHandler) GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.storage.GetWebhookByID(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to fetch webhook")
+		return
+	}
+	webhook.Secret = ""
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// EnableWebhook — POST /webhooks/{id}/enable: включает подписку, выключенную после череды неудач.
+func (h *WebhookHandler) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.storage.EnableWebhook(r.Context(), id)
+	if err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to enable webhook")
+		return
+	}
+	webhook.Secret = ""
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteWebhook(r.Context(), id); err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries — GET /webhooks/{id}/deliveries: журнал доставок подписки с фильтром по status.
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	page, err := parsePage(r, storage.DeliverySortFields)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.WebhookDeliveryFilter{WebhookID: id, Page: page}
+	filter.Status = r.URL.Query().Get("status")
+	if filter.Status != "" && !slices.Contains(models.DeliveryStatuses, filter.Status) {
+		problem.Write(w, r, http.StatusBadRequest, "query parameter status must be one of "+strings.Join(models.DeliveryStatuses, ", "))
+		return
+	}
+
+	// у несуществующей подписки — 404, а не пустой журнал
+	if _, err := h.storage.GetWebhookByID(r.Context(), id); err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to fetch webhook")
+		return
+	}
+	filter.Limit++ // лишняя запись — признак следующей страницы
+	deliveries, err := h.storage.GetWebhookDeliveries(r.Context(), filter)
+	if err != nil {
+		writeStorageError(w, r, err, "Webhook", "Failed to fetch deliveries")
+		return
+	}
+	writePage(w, r, deliveries, page, storage.DeliveryKey)
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,102 @@
+// Package health — проверки живости (/healthz) и готовности (/readyz) для оркестратора и балансировщика.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"TRYREST/internal/lib/logger/sl"
+)
+
+// Pinger — хранилище, доступность которого проверяет /readyz.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// SchemaVersioner — хранилище с версионированной схемой (postgre.Storage). memory.Storage его не реализует.
+type SchemaVersioner interface {
+	// SchemaVersion возвращает номер последней применённой миграции и флаг dirty (миграция упала на полпути).
+	SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// Checker отвечает на /healthz и /readyz.
+type Checker struct {
+	shutdown context.Context // отменяется, когда процесс начал останавливаться
+	db       Pinger
+	schema   SchemaVersioner // может быть nil
+	expected uint            // версия схемы, под которую собран бинарник
+	timeout  time.Duration
+	log      *slog.Logger
+}
+
+// New создаёт Checker. Как только shutdown отменён, /readyz отвечает 503, чтобы балансировщик
+// перестал слать трафик до srv.Shutdown. schema == nil отключает проверку миграций.
+func New(shutdown context.Context, db Pinger, schema SchemaVersioner, expected uint, timeout time.Duration, log *slog.Logger) *Checker {
+	return &Checker{shutdown: shutdown, db: db, schema: schema, expected: expected, timeout: timeout, log: log}
+}
+
+// status — тело ответа обеих проверок.
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness — GET /healthz: процесс жив и обслуживает запросы. Зависимости не проверяет,
+// иначе падение БД приводило бы к перезапуску всех реплик.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, status{Status: "ok"})
+}
+
+// Readiness — GET /readyz: можно ли слать трафик. 503, если идёт остановка, БД не отвечает
+// за timeout или схема отстаёт от ожидаемой версии.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	if c.shutdown.Err() != nil {
+		writeStatus(w, http.StatusServiceUnavailable, status{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok"}
+	ready := true
+	if err := c.db.Ping(ctx); err != nil {
+		c.log.WarnContext(ctx, "readiness: database ping failed", sl.Err(err))
+		checks["database"] = "unreachable"
+		ready = false
+	}
+	if c.schema != nil {
+		checks["schema"] = "ok"
+		version, dirty, err := c.schema.SchemaVersion(ctx)
+		switch {
+		case err != nil:
+			c.log.WarnContext(ctx, "readiness: schema version check failed", sl.Err(err))
+			checks["schema"] = "unknown"
+			ready = false
+		case dirty:
+			checks["schema"] = fmt.Sprintf("version %d is dirty", version)
+			ready = false
+		case version < c.expected:
+			checks["schema"] = fmt.Sprintf("version %d, expected %d", version, c.expected)
+			ready = false
+		}
+	}
+
+	if !ready {
+		writeStatus(w, http.StatusServiceUnavailable, status{Status: "not ready", Checks: checks})
+		return
+	}
+	writeStatus(w, http.StatusOK, status{Status: "ok", Checks: checks})
+}
+
+func writeStatus(w http.ResponseWriter, code int, body status) {
+	w.Header().Set("Content-Type", "application/json")
+	// пробы не должны попадать в кэши прокси
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,54 @@
+// Package holds — фоновое освобождение просроченных холдов.
+package holds
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/storage"
+)
+
+// Reaper раз в interval удаляет просроченные холды через storage.HoldRepository.
+// Места в выборке и так не учитывают просроченные холды; удаление нужно,
+// чтобы отдать освободившиеся места листу ожидания и не копить мусор.
+type Reaper struct {
+	storage  storage.HoldRepository
+	interval time.Duration
+	log      *slog.Logger
+	runner   loop.Runner
+}
+
+func NewReaper(storage storage.HoldRepository, interval time.Duration, log *slog.Logger) *Reaper {
+	return &Reaper{storage: storage, interval: interval, log: log}
+}
+
+// Start запускает фоновую горутину. Остановить её — Stop. При interval <= 0 reaper выключен.
+func (r *Reaper) Start() {
+	if r.interval <= 0 {
+		r.log.Warn("hold reaper disabled", slog.Duration("interval", r.interval))
+		return
+	}
+	r.runner.Start(r.interval, r.reap)
+}
+
+// Stop останавливает горутину и ждёт, пока она доделает текущий проход, но не дольше ctx.
+func (r *Reaper) Stop(ctx context.Context) error {
+	return r.runner.Stop(ctx)
+}
+
+// reap освобождает просроченные холды за один проход; ReleaseExpiredHolds забирает их все сразу.
+func (r *Reaper) reap(ctx context.Context) bool {
+	// проход не прерываем на полпути: Stop дожидается его завершения
+	released, err := r.storage.ReleaseExpiredHolds(context.WithoutCancel(ctx))
+	if err != nil {
+		r.log.Error("failed to release expired holds", sl.Err(err))
+		return false
+	}
+	if released > 0 {
+		r.log.Info("released expired holds", slog.Int("count", released))
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Package idempotency — поддержка заголовка Idempotency-Key для POST-запросов:
+// повтор запроса с тем же ключом получает сохранённый ответ вместо повторного выполнения.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"TRYREST/internal/auth"
+	"TRYREST/internal/lib/api/problem"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+const (
+	// Header — заголовок запроса с ключом.
+	Header = "Idempotency-Key"
+	// ReplayedHeader выставляется в "true" на повторённых ответах.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	maxBodyBytes = 1 << 20 // как у handlers.decodeJSON
+)
+
+// Middleware сохраняет ответ на запрос с заголовком Idempotency-Key на ttl и отдаёт его
+// при повторе с тем же ключом. Повтор ключа с другими методом, путём или телом — 422,
+// повтор во время выполнения первого запроса — 409. Ответы 5xx не сохраняются, чтобы
+// клиент мог повторить запрос. Ключи привязаны к пользователю, поэтому должен стоять после auth.Middleware.
+func Middleware(repo storage.IdempotencyRepository, ttl time.Duration, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", Header, maxKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			if len(body) > maxBodyBytes {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBodyBytes))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			identity, _ := auth.FromContext(r.Context())
+			rec, reserved, err := repo.ReserveIdempotencyKey(r.Context(), models.IdempotencyRecord{
+				UserID:      identity.UserID,
+				Key:         key,
+				Fingerprint: fingerprint(r, body),
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+			switch {
+			case errors.Is(err, storage.ErrConflict):
+				problem.Write(w, r, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+				return
+			case errors.Is(err, context.Canceled):
+				// клиент ушёл, отвечать некому
+				return
+			case errors.Is(err, context.DeadlineExceeded):
+				problem.Write(w, r, http.StatusGatewayTimeout, "Storage did not respond in time")
+				return
+			case errors.Is(err, storage.ErrUnavailable):
+				problem.Write(w, r, http.StatusServiceUnavailable, "Storage is temporarily unavailable")
+				return
+			case err != nil:
+				log.ErrorContext(r.Context(), "failed to reserve idempotency key", sl.Err(err))
+				problem.Write(w, r, http.StatusInternalServerError, "Failed to process Idempotency-Key")
+				return
+			}
+
+			if !reserved {
+				replay(w, r, rec, fingerprint(r, body))
+				return
+			}
+
+			// ответ сохраняем и ключ освобождаем, даже если клиент уже отключился:
+			// иначе ключ остался бы занятым до истечения ttl
+			saveCtx := context.WithoutCancel(r.Context())
+			// заголовки, выставленные до обработчика (внешними middleware), при повторе выставятся заново
+			outer := w.Header().Clone()
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// 5xx или паника — освобождаем ключ, чтобы запрос можно было повторить
+				if completed {
+					return
+				}
+				if err := repo.ReleaseIdempotencyKey(saveCtx, rec.UserID, key); err != nil {
+					log.ErrorContext(r.Context(), "failed to release idempotency key", sl.Err(err))
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= http.StatusInternalServerError {
+				return
+			}
+			err = repo.CompleteIdempotencyKey(saveCtx, rec.UserID, key, rw.status, handlerHeader(outer, rw.Header()), rw.body.Bytes())
+			if err != nil {
+				log.ErrorContext(r.Context(), "failed to save idempotent response", sl.Err(err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay отдаёт сохранённый ответ или ошибку, если ключ занят другим запросом.
+func replay(w http.ResponseWriter, r *http.Request, rec models.IdempotencyRecord, fp string) {
+	if rec.Fingerprint != fp {
+		problem.Write(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+		return
+	}
+	if !rec.Completed() {
+		problem.Write(w, r, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+		return
+	}
+	for name, values := range rec.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+// handlerHeader возвращает заголовки ответа, которые выставил или изменил обработчик:
+// их повторяем вместе с телом. Date и Content-Length сервер выставляет сам.
+func handlerHeader(outer, header http.Header) http.Header {
+	saved := make(http.Header)
+	for name, values := range header {
+		if name == "Date" || name == "Content-Length" || slices.Equal(outer[name], values) {
+			continue
+		}
+		saved[name] = slices.Clone(values)
+	}
+	return saved
+}
+
+// fingerprint — отпечаток запроса: метод, путь и тело байт в байт.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder пропускает ответ клиенту и запоминает статус и тело.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/storage/memory"
+)
+
+// TestReplayRestoresHeaders: повтор отдаёт заголовки, выставленные обработчиком, но не внешними middleware.
+func TestReplayRestoresHeaders(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	calls := 0
+	create := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/events/1")
+		w.Header().Set("ETag", `"1"`)
+		w.Header().Add("Link", `</events/1/bookings>; rel="bookings"`)
+		w.Header().Add("Link", `</events/1/holds>; rel="holds"`)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":1}`)
+	})
+	handler := Middleware(memory.New(log, bus.New()), time.Hour, log)(create)
+
+	post := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"title":"Go meetup"}`))
+		req.Header.Set(Header, "key-1")
+		rec := httptest.NewRecorder()
+		rec.Header().Set("X-Request-Id", requestID) // как внешний middleware
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	first := post("first")
+	replayed := post("second")
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", replayed.Code, replayed.Body, first.Code, first.Body)
+	}
+	for _, name := range []string{"Content-Type", "Location", "ETag", "Link"} {
+		if got, want := replayed.Header().Values(name), first.Header().Values(name); strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := replayed.Header().Get(ReplayedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", ReplayedHeader, got)
+	}
+	if got := replayed.Header().Get("X-Request-Id"); got != "second" {
+		t.Errorf("X-Request-Id = %q, want the one of the second request", got)
+	}
+}
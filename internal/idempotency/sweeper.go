@@ -0,0 +1,51 @@
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/storage"
+)
+
+// Sweeper раз в interval удаляет истёкшие ключи всех пользователей. Без него ключ удалялся бы
+// только при следующем запросе того же пользователя с тем же ключом, а ответы ушедших клиентов
+// копились бы в хранилище.
+type Sweeper struct {
+	storage  storage.IdempotencyRepository
+	interval time.Duration
+	log      *slog.Logger
+	runner   loop.Runner
+}
+
+func NewSweeper(storage storage.IdempotencyRepository, interval time.Duration, log *slog.Logger) *Sweeper {
+	return &Sweeper{storage: storage, interval: interval, log: log.With(slog.String("component", "idempotency"))}
+}
+
+// Start запускает фоновую горутину. Остановить её — Stop. При interval <= 0 очистка выключена.
+func (s *Sweeper) Start() {
+	if s.interval <= 0 {
+		s.log.Warn("idempotency key sweeper disabled", slog.Duration("interval", s.interval))
+		return
+	}
+	s.runner.Start(s.interval, s.sweep)
+}
+
+// Stop останавливает горутину и ждёт текущий проход, но не дольше ctx.
+func (s *Sweeper) Stop(ctx context.Context) error {
+	return s.runner.Stop(ctx)
+}
+
+func (s *Sweeper) sweep(ctx context.Context) bool {
+	purged, err := s.storage.PurgeExpiredIdempotencyKeys(context.WithoutCancel(ctx))
+	if err != nil {
+		s.log.Error("failed to purge expired idempotency keys", sl.Err(err))
+		return false
+	}
+	if purged > 0 {
+		s.log.Info("purged expired idempotency keys", slog.Int("count", purged))
+	}
+	return false
+}
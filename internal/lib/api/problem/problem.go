@@ -0,0 +1,96 @@
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ContentType — media type ошибок по RFC 7807.
+const ContentType = "application/problem+json"
+
+// Problem — тело ответа с ошибкой (RFC 7807). Все обработчики API отвечают на ошибки только им.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError — ошибка в конкретном поле запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New создаёт Problem с type "about:blank" и стандартным заголовком для статуса.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write отвечает клиенту ошибкой status с пояснением detail.
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	New(status, detail).Write(w, r)
+}
+
+// Write дописывает instance и request id из запроса и отправляет ошибку клиенту.
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = middleware.GetReqID(r.Context())
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// NotFound — обработчик для router.NotFound.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusNotFound, "No route for "+r.URL.Path)
+}
+
+// MethodNotAllowed — обработчик для router.MethodNotAllowed.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed for "+r.URL.Path)
+}
+
+// Recoverer — замена middleware.Recoverer: логирует панику со стеком и отвечает 500 в формате problem+json.
+func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					// соединение и так будет разорвано, просто пробрасываем дальше
+					panic(rvr)
+				}
+				log.ErrorContext(r.Context(), "panic recovered",
+					slog.Any("panic", rvr),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if r.Header.Get("Connection") != "Upgrade" {
+					Write(w, r, http.StatusInternalServerError, "Internal server error")
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
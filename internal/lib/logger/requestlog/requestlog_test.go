@@ -0,0 +1,57 @@
+package requestlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"TRYREST/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogsWithRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(tracing.LogHandler{Handler: slog.NewJSONHandler(&buf, nil)})
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler { // как tracing.Middleware: спан в ctx запроса
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(trace.ContextWithSpanContext(r.Context(), sc)))
+		})
+	})
+	router.Use(New(log))
+	router.Get("/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events/7", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode log entry %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":    "ERROR",
+		"method":   "GET",
+		"path":     "/events/7",
+		"route":    "/events/{id}",
+		"status":   float64(500),
+		"bytes":    float64(4),
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package loop — жизненный цикл фоновых задач, которые выполняются по таймеру.
+package loop
+
+import (
+	"context"
+	"time"
+)
+
+// Runner раз в interval вызывает tick в отдельной горутине. Нулевое значение готово к работе.
+// Если tick вернул true, работы, вероятно, больше, чем он успел сделать (полная пачка), и он
+// вызывается снова, не дожидаясь следующего тика.
+//
+// ctx, переданный в tick, отменяется в Stop. Начатую работу tick доводит до конца под
+// context.WithoutCancel(ctx), а между частями проверяет ctx.Err(): Stop дожидается текущего вызова.
+type Runner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start запускает горутину. Вызывать один раз; interval должен быть больше нуля.
+func (r *Runner) Start(interval time.Duration, tick func(ctx context.Context) bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, interval, tick)
+}
+
+// Stop останавливает горутину и ждёт текущий вызов tick, но не дольше ctx. Без Start ничего не делает.
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) run(ctx context.Context, interval time.Duration, tick func(ctx context.Context) bool) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil && tick(ctx) {
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package loop
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunnerRepeatsWhileMore(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	var calls []time.Time // пишет только горутина Runner, читаем после Stop
+	third := make(chan struct{})
+	var r Runner
+	r.Start(interval, func(ctx context.Context) bool {
+		calls = append(calls, time.Now())
+		if len(calls) == 3 {
+			close(third)
+		}
+		// первые три вызова — «полная пачка», дальше ждём тика
+		return len(calls) < 3
+	})
+	<-third
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("calls = %d, want 3", len(calls))
+	}
+	if gap := calls[2].Sub(calls[0]); gap >= interval {
+		t.Errorf("repeated calls took %s, want them within one tick", gap)
+	}
+}
+
+func TestRunnerStopWaitsForTick(t *testing.T) {
+	started := make(chan struct{})
+	var finished atomic.Bool
+	var r Runner
+	r.Start(time.Millisecond, func(ctx context.Context) bool {
+		if finished.Load() {
+			return false
+		}
+		close(started)
+		<-ctx.Done() // Stop отменяет ctx, но ждёт, пока tick вернётся
+		time.Sleep(10 * time.Millisecond)
+		finished.Store(true)
+		return true
+	})
+	<-started
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Stop returned before tick finished")
+	}
+}
+
+func TestRunnerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	var r Runner
+	r.Start(time.Millisecond, func(ctx context.Context) bool {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return false
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Stop = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRunnerStopWithoutStart(t *testing.T) {
+	var r Runner
+	if err := r.Stop(context.Background()); err != nil {
+		t.Errorf("Stop = %v, want nil", err)
+	}
+}
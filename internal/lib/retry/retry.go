@@ -0,0 +1,13 @@
+// Package retry — общие расчёты для повторов: outbox, вебхуки и письма откладывают неудачные попытки одинаково.
+package retry
+
+import "time"
+
+// Backoff — задержка перед повтором после attempts неудачных попыток: base * 2^attempts, но не больше limit.
+func Backoff(attempts int, base, limit time.Duration) time.Duration {
+	delay := base
+	for i := 0; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
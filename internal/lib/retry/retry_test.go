@@ -0,0 +1,24 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{5, 30 * time.Second},
+		{1000, 30 * time.Second}, // без переполнения
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, time.Second, 30*time.Second); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
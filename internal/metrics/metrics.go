@@ -0,0 +1,191 @@
+// Package metrics — метрики Prometheus: HTTP-запросы, пул соединений БД и доменные счётчики бронирований.
+package metrics
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"TRYREST/internal/bus"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "booker"
+
+// Источники бронирований для метки source у bookings_created_total.
+const (
+	SourceDirect   = "direct"   // POST /bookings
+	SourceHold     = "hold"     // подтверждение холда
+	SourceWaitlist = "waitlist" // продвижение из листа ожидания
+)
+
+// Metrics держит собственный реестр, чтобы /metrics отдавал только метрики приложения и рантайма.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	// потоки text/event-stream живут часами и не попадают в duration и inFlight
+	streams        prometheus.Gauge
+	streamDuration *prometheus.HistogramVec
+
+	bookingsCreated   *prometheus.CounterVec
+	bookingsCancelled prometheus.Counter
+	soldOut           prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served, excluding event streams.",
+		}),
+		streams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_streams_active",
+			Help:      "Open text/event-stream responses.",
+		}),
+		streamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_stream_duration_seconds",
+			Help:      "How long text/event-stream responses stayed open, by route pattern.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8), // от секунды до ~4.5 часов
+		}, []string{"route"}),
+		bookingsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bookings_created_total",
+			Help:      "Bookings created, by source: direct, hold or waitlist.",
+		}, []string{"source"}),
+		bookingsCancelled: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bookings_cancelled_total",
+			Help:      "Bookings moved to the cancelled status.",
+		}),
+		soldOut: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bookings_sold_out_rejections_total",
+			Help:      "Bookings and holds rejected because the event had no free seats.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.inFlight, m.streams, m.streamDuration,
+		m.bookingsCreated, m.bookingsCancelled, m.soldOut,
+	)
+	// чтобы серии были видны в /metrics с нуля, до первого бронирования
+	for _, source := range []string{SourceDirect, SourceHold, SourceWaitlist} {
+		m.bookingsCreated.WithLabelValues(source)
+	}
+	return m
+}
+
+// Handler отдаёт метрики в формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware считает запросы и их длительность. Метка route — шаблон маршрута chi (/events/{id}),
+// а не путь, иначе число серий росло бы с каждым id. Должен стоять в router.Use до Recoverer,
+// чтобы паники попадали в метрики как 500. Ответ text/event-stream с момента отправки заголовков
+// считается потоком: он уходит из http_requests_in_flight, а его длительность — в http_stream_duration_seconds.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		start := time.Now()
+		sw := &streamWriter{ResponseWriter: middleware.NewWrapResponseWriter(w, r.ProtoMajor)}
+		sw.onStream = func() {
+			m.inFlight.Dec()
+			m.streams.Inc()
+		}
+		defer func() {
+			if sw.streaming {
+				m.streams.Dec()
+			} else {
+				m.inFlight.Dec()
+			}
+		}()
+		ww := sw.ResponseWriter.(middleware.WrapResponseWriter)
+		next.ServeHTTP(sw, r)
+
+		// шаблон известен только после маршрутизации
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{r.Method, route, strconv.Itoa(status)}
+		m.requests.WithLabelValues(labels...).Inc()
+		if sw.streaming {
+			m.streamDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+			return
+		}
+		m.duration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// streamWriter замечает, что обработчик начал ответ text/event-stream.
+// Unwrap нужен http.ResponseController: через него поток снимает дедлайны и сбрасывает буфер.
+type streamWriter struct {
+	http.ResponseWriter
+	onStream  func()
+	started   bool
+	streaming bool
+}
+
+func (w *streamWriter) WriteHeader(status int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *streamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start проверяет тип ответа при первой отправке заголовков.
+func (w *streamWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "text/event-stream" {
+		w.streaming = true
+		w.onStream()
+	}
+}
+
+// Observe — подписчик шины: бронирования из листа ожидания создаются внутри хранилища,
+// мимо InstrumentStorage, и видны только по сообщениям.
+func (m *Metrics) Observe(msg bus.Message) {
+	if msg.Type == bus.WaitlistPromoted {
+		m.bookingsCreated.WithLabelValues(SourceWaitlist).Inc()
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// scrape возвращает строки /metrics с метриками booker_http_*.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var lines []string
+	for line := range strings.Lines(rec.Body.String()) {
+		if strings.HasPrefix(line, "booker_http_") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// TestStreamsAreCountedSeparately: открытый поток не висит в http_requests_in_flight,
+// а его длительность не попадает в гистограмму задержек обычных запросов.
+func TestStreamsAreCountedSeparately(t *testing.T) {
+	m := New()
+	release := make(chan struct{})
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Get("/events/{id}/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "retry: 2000\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+		<-release
+	})
+	router.Get("/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "{}")
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events/1/stream")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	got := scrape(t, m)
+	for _, want := range []string{"booker_http_requests_in_flight 0\n", "booker_http_streams_active 1\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics while streaming lack %q:\n%s", want, got)
+		}
+	}
+
+	close(release)
+	io.Copy(io.Discard, resp.Body)
+	plain, err := http.Get(srv.URL + "/events/1")
+	if err != nil {
+		t.Fatalf("GET event: %v", err)
+	}
+	plain.Body.Close()
+
+	got = scrape(t, m)
+	for _, want := range []string{
+		"booker_http_streams_active 0\n",
+		`booker_http_stream_duration_seconds_count{route="/events/{id}/stream"} 1` + "\n",
+		`booker_http_request_duration_seconds_count{method="GET",route="/events/{id}",status="200"} 1` + "\n",
+		`booker_http_requests_total{method="GET",route="/events/{id}/stream",status="200"} 1` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics lack %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `booker_http_request_duration_seconds_count{method="GET",route="/events/{id}/stream"`) {
+		t.Errorf("stream is in the request latency histogram:\n%s", got)
+	}
+}
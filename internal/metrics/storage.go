@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// InstrumentStorage оборачивает хранилище доменными счётчиками: созданные и отменённые бронирования
+// и отказы из-за распроданного события. Одинаково работает с postgres и memory.
+// Бронирования из листа ожидания считает Observe.
+func InstrumentStorage(s storage.Storage, m *Metrics) storage.Storage {
+	return &instrumented{Storage: s, m: m}
+}
+
+type instrumented struct {
+	storage.Storage
+	m *Metrics
+}
+
+func (s *instrumented) AddBooking(ctx context.Context, eventID, userID int64) (int64, error) {
+	id, err := s.Storage.AddBooking(ctx, eventID, userID)
+	s.countSoldOut(err)
+	if err == nil {
+		s.m.bookingsCreated.WithLabelValues(SourceDirect).Inc()
+	}
+	return id, err
+}
+
+func (s *instrumented) UpdateBooking(ctx context.Context, id, eventID, userID, version int64) error {
+	err := s.Storage.UpdateBooking(ctx, id, eventID, userID, version)
+	s.countSoldOut(err)
+	return err
+}
+
+func (s *instrumented) PatchBooking(ctx context.Context, id, version int64, patch storage.BookingPatch) error {
+	err := s.Storage.PatchBooking(ctx, id, version, patch)
+	s.countSoldOut(err)
+	return err
+}
+
+func (s *instrumented) SetBookingStatus(ctx context.Context, id int64, status string) (models.Booking, error) {
+	booking, err := s.Storage.SetBookingStatus(ctx, id, status)
+	if err == nil && status == models.BookingCancelled {
+		s.m.bookingsCancelled.Inc()
+	}
+	return booking, err
+}
+
+func (s *instrumented) AddHold(ctx context.Context, eventID, userID int64, ttl time.Duration) (models.Hold, error) {
+	hold, err := s.Storage.AddHold(ctx, eventID, userID, ttl)
+	s.countSoldOut(err)
+	return hold, err
+}
+
+func (s *instrumented) ConfirmHold(ctx context.Context, id int64) (models.Booking, error) {
+	booking, err := s.Storage.ConfirmHold(ctx, id)
+	if err == nil {
+		s.m.bookingsCreated.WithLabelValues(SourceHold).Inc()
+	}
+	return booking, err
+}
+
+func (s *instrumented) countSoldOut(err error) {
+	if errors.Is(err, storage.ErrEventFull) {
+		s.m.soldOut.Inc()
+	}
+}
@@ -0,0 +1,156 @@
+// Package migrator применяет встроенные миграции (migrations.FS) через golang-migrate.
+// Версия схемы хранится в той же таблице schema_migrations, что и у образа docker/migrator,
+// поэтому оба способа можно смешивать.
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"TRYREST/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Latest возвращает номер последней встроенной миграции — версию схемы, под которую собран бинарник.
+func Latest() uint {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return 0 // встроенная ФС без каталога невозможна
+	}
+	var latest uint
+	for _, entry := range entries {
+		m, err := source.Parse(entry.Name())
+		if err == nil && m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// Status — состояние схемы относительно встроенных миграций.
+type Status struct {
+	Version uint // последняя применённая миграция, 0 — ни одной
+	Dirty   bool // миграция Version упала на полпути, схему нужно чинить вручную
+	Latest  uint
+}
+
+// Migrator держит собственное соединение с БД: миграции выполняются до создания хранилища.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New подключается к БД по dsn. Закрывать через Close.
+func New(dsn string, log *slog.Logger) (*Migrator, error) {
+	const op = "migrator.New"
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: source: %w", op, err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// драйвер сам проверяет соединение и берёт advisory lock на время миграций,
+	// так что несколько реплик с auto_migrate не применят миграцию дважды
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: database: %w", op, err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	m.Log = logger{log}
+	return &Migrator{m: m}, nil
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *Migrator) Up() error {
+	const op = "migrator.Up"
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Down откатывает одну последнюю миграцию.
+func (m *Migrator) Down() error {
+	const op = "migrator.Down"
+	if err := m.m.Steps(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Goto применяет или откатывает миграции до версии version.
+func (m *Migrator) Goto(version uint) error {
+	const op = "migrator.Goto"
+	if version > Latest() {
+		return fmt.Errorf("%s: version %d does not exist, latest is %d", op, version, Latest())
+	}
+	var err error
+	if version == 0 {
+		err = m.m.Down() // у golang-migrate нет миграции с номером 0
+	} else {
+		err = m.m.Migrate(version)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Force записывает версию version как применённую и снимает флаг dirty, ничего не выполняя.
+// Нужен после ручного исправления схемы, на которой упала миграция.
+func (m *Migrator) Force(version uint) error {
+	const op = "migrator.Force"
+	if version > Latest() {
+		return fmt.Errorf("%s: version %d does not exist, latest is %d", op, version, Latest())
+	}
+	v := int(version)
+	if version == 0 {
+		v = -1 // «ни одной миграции» в терминах golang-migrate
+	}
+	if err := m.m.Force(v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (m *Migrator) Status() (Status, error) {
+	const op = "migrator.Status"
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return Status{Version: version, Dirty: dirty, Latest: Latest()}, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// logger пишет сообщения golang-migrate ("1/u init_schema (12ms)") в slog.
+type logger struct {
+	log *slog.Logger
+}
+
+func (l logger) Printf(format string, v ...any) {
+	l.log.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,47 @@
+package models
+
+// Пределы длины пароля; bcrypt учитывает только первые 72 байта.
+const (
+	minPasswordLen = 8
+	maxPasswordLen = 72
+)
+
+// Registration — тело POST /auth/register.
+type Registration struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (r Registration) Validate() error {
+	var v validator
+	v.text(r.Name, "name", maxVarchar)
+	v.email(r.Email, "email")
+	v.check(len(r.Password) >= minPasswordLen, "password", "must be at least 8 characters")
+	v.check(len(r.Password) <= maxPasswordLen, "password", "must be at most 72 bytes")
+	return v.err()
+}
+
+// Credentials — тело POST /auth/login.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (c Credentials) Validate() error {
+	var v validator
+	v.check(c.Email != "", "email", "is required")
+	v.check(c.Password != "", "password", "is required")
+	return v.err()
+}
+
+// RefreshRequest — тело POST /auth/refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r RefreshRequest) Validate() error {
+	var v validator
+	v.check(r.RefreshToken != "", "refresh_token", "is required")
+	return v.err()
+}
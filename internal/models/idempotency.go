@@ -0,0 +1,27 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord — сохранённый ответ на запрос с заголовком Idempotency-Key.
+// Ключи у каждого пользователя свои.
+type IdempotencyRecord struct {
+	UserID int64
+	Key    string
+	// Fingerprint — sha256 метода, пути и тела запроса: повтор ключа с другим запросом отклоняется
+	Fingerprint string
+	// StatusCode == 0 — первый запрос с этим ключом ещё выполняется
+	StatusCode int
+	// Header — заголовки, выставленные обработчиком (Content-Type, Location, ETag, ...)
+	Header    http.Header
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Completed сообщает, что ответ уже сохранён и его можно повторить.
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -1,19 +1,135 @@
 package models
 
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Роли пользователей. Права каждой роли хранятся в таблице role_permissions.
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleAttendee  = "attendee"
+)
+
+// Roles — все известные роли.
+var Roles = []string{RoleAdmin, RoleOrganizer, RoleAttendee}
+
 type User struct {
 	ID    int64  `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	Role  string `json:"role"`
+	// PasswordHash — bcrypt-хеш, наружу не отдаётся. Пустой у пользователей без пароля.
+	PasswordHash string `json:"-"`
+	// Version растёт при каждом изменении; наружу отдаётся только как ETag
+	Version int64 `json:"-"`
 }
 
 type Event struct {
-	ID          int64  `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	// TimeZone — название зоны из базы IANA (Europe/Moscow), в ней отдаются start_at и end_at
+	TimeZone string `json:"time_zone"`
+	Capacity int    `json:"capacity"`
+	// OrganizerID — владелец события; 0, если организатор удалён или событие создано до появления ролей
+	OrganizerID int64 `json:"organizer_id"`
+	// RemainingSeats вычисляется хранилищем: capacity минус неотменённые бронирования и действующие холды
+	RemainingSeats int `json:"remaining_seats"`
+	// Version растёт при каждом изменении; наружу отдаётся только как ETag
+	Version int64 `json:"-"`
+}
+
+// MarshalJSON отдаёт start_at и end_at в RFC 3339 со смещением часового пояса события.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event // без методов, чтобы не уйти в рекурсию
+	if loc, err := time.LoadLocation(e.TimeZone); err == nil && e.TimeZone != "" {
+		e.StartAt = e.StartAt.In(loc)
+		e.EndAt = e.EndAt.In(loc)
+	}
+	return json.Marshal(event(e))
+}
+
+// SameSchedule сообщает, что у событий совпадают начало, окончание и часовой пояс — то, о чём уведомляют участников.
+func (e Event) SameSchedule(other Event) bool {
+	return e.StartAt.Equal(other.StartAt) && e.EndAt.Equal(other.EndAt) && e.TimeZone == other.TimeZone
+}
+
+// Статусы бронирования. Отменённое бронирование не занимает место, но остаётся в истории.
+const (
+	BookingPending   = "pending"
+	BookingConfirmed = "confirmed"
+	BookingCancelled = "cancelled"
+	BookingAttended  = "attended"
+)
+
+// BookingStatuses — все известные статусы.
+var BookingStatuses = []string{BookingPending, BookingConfirmed, BookingCancelled, BookingAttended}
+
+// bookingTransitions — разрешённые переходы; cancelled и attended конечные.
+var bookingTransitions = map[string][]string{
+	BookingPending:   {BookingConfirmed, BookingCancelled},
+	BookingConfirmed: {BookingCancelled, BookingAttended},
 }
 
 type Booking struct {
+	ID      int64  `json:"id"`
+	EventID int64  `json:"event_id"`
+	UserID  int64  `json:"user_id"`
+	Status  string `json:"status"`
+	// время создания и каждого перехода; nil, если перехода не было
+	CreatedAt   time.Time  `json:"created_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	AttendedAt  *time.Time `json:"attended_at,omitempty"`
+	// Version растёт при каждом изменении, включая смену статуса; наружу отдаётся только как ETag
+	Version int64 `json:"-"`
+}
+
+// CanTransitionTo сообщает, можно ли перевести бронирование в статус status.
+func (b Booking) CanTransitionTo(status string) bool {
+	return slices.Contains(bookingTransitions[b.Status], status)
+}
+
+// Active сообщает, что бронирование ещё можно менять: оно не отменено и не отмечено посещённым.
+func (b Booking) Active() bool {
+	return b.Status == BookingPending || b.Status == BookingConfirmed
+}
+
+// WaitlistEntry — место пользователя в очереди на распроданное событие.
+type WaitlistEntry struct {
 	ID      int64 `json:"id"`
 	EventID int64 `json:"event_id"`
 	UserID  int64 `json:"user_id"`
+	// Position — номер в очереди, начиная с 1; вычисляется хранилищем
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Hold — место, удерживаемое за пользователем до ExpiresAt, пока он оформляет бронирование.
+type Hold struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OutboxMessage — доменное событие из outbox: записано в одной транзакции с изменением сущности
+// и доставляется получателям как минимум один раз.
+type OutboxMessage struct {
+	// ID растёт в порядке записи; получатели отбрасывают по нему повторы
+	ID            int64  `json:"id"`
+	Type          string `json:"type"`           // bus.BookingCreated, bus.EventUpdated...
+	AggregateType string `json:"aggregate_type"` // "user", "event", "booking"
+	AggregateID   int64  `json:"aggregate_id"`
+	// Payload — снимок сущности в JSON после изменения, у *Deleted — перед удалением
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// Attempts — сколько попыток доставки уже не удалось
+	Attempts int `json:"-"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Статусы напоминания о событии.
+const (
+	ReminderPending   = "pending"   // ждёт срока или отправки
+	ReminderSent      = "sent"      // письмо поставлено в очередь
+	ReminderCancelled = "cancelled" // бронирование отменено, событие началось или напоминание устарело
+)
+
+// Reminder — письмо участнику за Offset до начала события, на которое у него бронирование.
+type Reminder struct {
+	ID        int64         `json:"id"`
+	BookingID int64         `json:"booking_id"`
+	Offset    time.Duration `json:"offset"`
+	// DueAt — начало события минус Offset; пересчитывается при переносе события
+	DueAt  time.Time  `json:"due_at"`
+	Status string     `json:"status"`
+	SentAt *time.Time `json:"sent_at,omitempty"`
+}
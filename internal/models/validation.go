@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxVarchar — длина колонок VARCHAR(255) в миграциях.
+const maxVarchar = 255
+
+// FieldError — нарушенное правило в одном поле.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError перечисляет все невалидные поля объекта, а не только первое.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// validator накапливает ошибки по полям.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.fields = append(v.fields, FieldError{Field: field, Message: message})
+	}
+}
+
+// text проверяет обязательную строку длиной не больше max символов.
+func (v *validator) text(value, field string, max int) {
+	switch {
+	case strings.TrimSpace(value) == "":
+		v.check(false, field, "is required")
+	case utf8.RuneCountInString(value) > max:
+		v.check(false, field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// email проверяет обязательный адрес без отображаемого имени ("a@b.c", но не "A <a@b.c>").
+func (v *validator) email(value, field string) {
+	v.text(value, field, maxVarchar)
+	if value != "" {
+		addr, err := mail.ParseAddress(value)
+		v.check(err == nil && addr.Address == value, field, "must be a valid email address")
+	}
+}
+
+func (v *validator) id(value int64, field string) {
+	v.check(value > 0, field, "must be a positive integer")
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// Validate проверяет поля пользователя, приходящие от клиента.
+func (u User) Validate() error {
+	var v validator
+	v.text(u.Name, "name", maxVarchar)
+	v.email(u.Email, "email")
+	v.check(u.Role == "" || slices.Contains(Roles, u.Role), "role", "must be one of "+strings.Join(Roles, ", "))
+	return v.err()
+}
+
+// Validate проверяет поля события, приходящие от клиента.
+func (e Event) Validate() error {
+	var v validator
+	v.text(e.Title, "title", maxVarchar)
+	v.check(e.Capacity > 0, "capacity", "must be positive")
+	v.check(!e.StartAt.IsZero(), "start_at", "is required")
+	v.check(!e.EndAt.IsZero(), "end_at", "is required")
+	if !e.StartAt.IsZero() && !e.EndAt.IsZero() {
+		v.check(e.EndAt.After(e.StartAt), "end_at", "must be after start_at")
+	}
+	_, err := time.LoadLocation(e.TimeZone)
+	v.check(err == nil && e.TimeZone != "" && e.TimeZone != "Local" && len(e.TimeZone) <= 64,
+		"time_zone", "must be an IANA time zone name, e.g. Europe/Moscow")
+	return v.err()
+}
+
+// Validate проверяет поля бронирования, приходящие от клиента.
+func (b Booking) Validate() error {
+	var v validator
+	v.id(b.EventID, "event_id")
+	v.id(b.UserID, "user_id")
+	return v.err()
+}
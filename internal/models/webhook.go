@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"TRYREST/internal/bus"
+)
+
+// Статусы доставки вебхука.
+const (
+	DeliveryPending   = "pending"   // ждёт первой или повторной попытки
+	DeliverySucceeded = "succeeded" // получатель ответил 2xx
+	DeliveryFailed    = "failed"    // попытки исчерпаны
+)
+
+// DeliveryStatuses — все статусы доставки.
+var DeliveryStatuses = []string{DeliveryPending, DeliverySucceeded, DeliveryFailed}
+
+// Webhook — подписка партнёра на доменные события (bus.WebhookTypes).
+type Webhook struct {
+	ID         int64    `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret — ключ HMAC-подписи; клиенту отдаётся только в ответе на создание
+	Secret string `json:"secret,omitempty"`
+	Active bool   `json:"active"`
+	// ConsecutiveFailures — неудачные попытки подряд; на пороге webhooks.max_failures подписка выключается
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// Subscribed сообщает, нужно ли доставлять подписке сообщения типа msgType.
+func (w Webhook) Subscribed(msgType string) bool {
+	return w.Active && slices.Contains(w.EventTypes, msgType)
+}
+
+// Validate проверяет поля подписки, приходящие от клиента.
+func (w Webhook) Validate() error {
+	var v validator
+	u, err := url.Parse(w.URL)
+	v.check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && len(w.URL) <= 2048,
+		"url", "must be an absolute http(s) URL of at most 2048 characters")
+	v.check(len(w.EventTypes) > 0, "event_types", "must not be empty")
+	for _, t := range w.EventTypes {
+		if !slices.Contains(bus.WebhookTypes, t) {
+			v.check(false, "event_types", "must contain only "+strings.Join(bus.WebhookTypes, ", "))
+			break
+		}
+	}
+	n := utf8.RuneCountInString(w.Secret)
+	v.check(n >= 16 && n <= maxVarchar, "secret", "must be between 16 and 255 characters")
+	return v.err()
+}
+
+// WebhookDelivery — доставка одного сообщения outbox одной подписке и итог последней попытки.
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	// MessageID — models.OutboxMessage.ID; получатель отбрасывает по нему повторы
+	MessageID int64  `json:"message_id"`
+	EventType string `json:"event_type"`
+	// Payload — тело запроса: сообщение outbox целиком
+	Payload  json.RawMessage `json:"payload"`
+	Status   string          `json:"status"`
+	Attempts int             `json:"attempts"`
+	// ResponseStatus — HTTP-код последней попытки; 0 — ответа не было
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"` // только у pending
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
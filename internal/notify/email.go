@@ -0,0 +1,149 @@
+// Package notify — письма пользователям о бронированиях и изменениях событий: шаблоны,
+// асинхронная очередь с повторами и отправители (SMTP, файлы, лог).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Email — готовое к отправке письмо.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Kind — шаблон, из которого собрано письмо (booking_created...); только для логов
+	Kind string
+}
+
+// Sender отправляет письмо. Ошибка, обёрнутая в Permanent, не повторяется.
+type Sender interface {
+	Send(ctx context.Context, from string, email Email) error
+}
+
+// permanentError — ошибка, после которой повтор бессмысленен (например, адрес отвергнут сервером).
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку как неисправимую повтором.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Bytes собирает письмо в формате RFC 5322: multipart/alternative с текстовой и HTML-частью
+// в quoted-printable, тема в кодировке RFC 2047.
+func (e Email) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", e.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", e.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `multipart/alternative; boundary="`+body.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID строит уникальный Message-ID в домене отправителя.
+func messageID(from string) string {
+	domain := "booker.local"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+	random := make([]byte, 12)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// LogSender пишет письма в лог вместо отправки — для локальной разработки.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(ctx context.Context, from string, email Email) error {
+	s.log.InfoContext(ctx, "email",
+		slog.String("kind", email.Kind),
+		slog.String("from", from),
+		slog.String("to", email.To),
+		slog.String("subject", email.Subject),
+		slog.String("text", email.Text),
+	)
+	return nil
+}
+
+// FileSender сохраняет каждое письмо в отдельный .eml-файл каталога dir — его открывает
+// любой почтовый клиент, так удобно проверять вёрстку без SMTP.
+type FileSender struct {
+	dir string
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create mail directory: %w", err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, from string, email Email) error {
+	data, err := email.Bytes(from)
+	if err != nil {
+		return Permanent(err)
+	}
+	random := make([]byte, 4)
+	rand.Read(random)
+	name := fmt.Sprintf("%s-%s-%s.eml", time.Now().Format("20060102-150405.000"), email.Kind, hex.EncodeToString(random))
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0o644)
+}
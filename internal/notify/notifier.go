@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Repository — то, что нужно Notifier из хранилища.
+type Repository interface {
+	storage.NotificationRepository
+	GetUserByID(ctx context.Context, id int64) (models.User, error)
+	GetEventByID(ctx context.Context, id int64) (models.Event, error)
+	GetAllBookings(ctx context.Context, filter storage.BookingFilter) ([]models.Booking, error)
+}
+
+// Notifier — получатель outbox: превращает BookingCreated, BookingCancelled и EventRescheduled
+// в письма и ставит их в очередь. Остальные сообщения пропускает. Напоминания о событиях
+// приходят не из outbox, а от планировщика через Remind.
+//
+// Письма по сообщению ставятся в очередь один раз: перед этим Notifier ставит сообщению отметку
+// в хранилище, и повтор того же сообщения после сбоя или перезапуска её видит.
+type Notifier struct {
+	storage   Repository
+	templates *Templates
+	queue     *Queue
+	locale    string
+	log       *slog.Logger
+}
+
+func NewNotifier(storage Repository, templates *Templates, queue *Queue, locale string, log *slog.Logger) (*Notifier, error) {
+	if !knownLocale(locale) {
+		return nil, fmt.Errorf("unknown notification locale %q, expected one of %v", locale, Locales)
+	}
+	return &Notifier{
+		storage:   storage,
+		templates: templates,
+		queue:     queue,
+		locale:    locale,
+		log:       log.With(slog.String("component", "notify")),
+	}, nil
+}
+
+func (n *Notifier) Send(ctx context.Context, msg models.OutboxMessage) error {
+	emails, err := n.emails(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("message %d: %w", msg.ID, err)
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+	reserved, err := n.storage.ReserveNotification(ctx, msg.ID)
+	if errors.Is(err, storage.ErrForeignKey) {
+		return nil // сообщение уже подтвердил другой инстанс
+	}
+	if err != nil {
+		return fmt.Errorf("message %d: %w", msg.ID, err)
+	}
+	if !reserved {
+		return nil // повтор после сбоя другого получателя или перезапуска: письма уже в очереди
+	}
+	if err := n.queue.Enqueue(emails...); err != nil {
+		if rerr := n.storage.ReleaseNotification(ctx, msg.ID); rerr != nil {
+			n.log.ErrorContext(ctx, "failed to release notification mark", slog.Int64("id", msg.ID), slog.Any("error", rerr))
+		}
+		return err
+	}
+	return nil
+}
+
+// Remind ставит в очередь напоминание о событии владельцу бронирования. Удалённому пользователю
+// ничего не отправляется.
+func (n *Notifier) Remind(ctx context.Context, event models.Event, booking models.Booking) error {
+	email, err := n.render(ctx, KindEventReminder, event, booking)
+	if err != nil || email == nil {
+		return err
+	}
+	return n.queue.Enqueue(*email)
+}
+
+// emails собирает письма для сообщения.
+func (n *Notifier) emails(ctx context.Context, msg models.OutboxMessage) ([]Email, error) {
+	switch msg.Type {
+	case bus.BookingCreated, bus.BookingCancelled:
+		var booking models.Booking
+		if err := json.Unmarshal(msg.Payload, &booking); err != nil {
+			return nil, fmt.Errorf("decode booking: %w", err)
+		}
+		kind := KindBookingCreated
+		if msg.Type == bus.BookingCancelled {
+			kind = KindBookingCancelled
+		}
+		event, err := n.storage.GetEventByID(ctx, booking.EventID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil // событие удалено вместе с бронированием
+		}
+		if err != nil {
+			return nil, err
+		}
+		email, err := n.render(ctx, kind, event, booking)
+		if err != nil || email == nil {
+			return nil, err
+		}
+		return []Email{*email}, nil
+
+	// о прочих изменениях события (название, описание, вместимость) участникам не пишем
+	case bus.EventRescheduled:
+		var event models.Event
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		bookings, err := n.storage.GetAllBookings(ctx, storage.BookingFilter{EventID: event.ID})
+		if err != nil {
+			return nil, err
+		}
+		var emails []Email
+		for _, booking := range bookings {
+			if !booking.Active() {
+				continue
+			}
+			email, err := n.render(ctx, KindEventChanged, event, booking)
+			if err != nil {
+				return nil, err
+			}
+			if email != nil {
+				emails = append(emails, *email)
+			}
+		}
+		return emails, nil
+	}
+	return nil, nil
+}
+
+// render собирает письмо владельцу бронирования; nil — пользователь удалён.
+func (n *Notifier) render(ctx context.Context, kind string, event models.Event, booking models.Booking) (*Email, error) {
+	user, err := n.storage.GetUserByID(ctx, booking.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	email, err := n.templates.Render(kind, n.locale, Data{User: user, Event: event, Booking: booking})
+	if err != nil {
+		// шаблоны проверены при старте; ошибка здесь не исправится повтором
+		n.log.ErrorContext(ctx, "failed to render email", slog.String("kind", kind), slog.Int64("booking_id", booking.ID), slog.Any("error", err))
+		return nil, nil
+	}
+	return &email, nil
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/config"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage/memory"
+)
+
+// notifierFixture — Notifier поверх memory-хранилища с одним бронированием; воркеры очереди
+// не запущены, письма остаются в q.jobs.
+type notifierFixture struct {
+	t        *testing.T
+	storage  *memory.Storage
+	queue    *Queue
+	notifier *Notifier
+	event    models.Event
+}
+
+func newNotifierFixture(t *testing.T) *notifierFixture {
+	t.Helper()
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.New(log, bus.New())
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	queue := NewQueue(NewLogSender(log), config.Notifications{QueueSize: 10}, log)
+	notifier, err := NewNotifier(s, templates, queue, "en", log)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	userID, err := s.AddUser(ctx, models.User{Name: "Ann", Email: "ann@example.com", Role: models.RoleAttendee})
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	event := models.Event{Title: "Go meetup", StartAt: start, EndAt: start.Add(2 * time.Hour), TimeZone: "UTC", Capacity: 10}
+	if event.ID, err = s.AddEvent(ctx, event); err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	if _, err := s.AddBooking(ctx, event.ID, userID); err != nil {
+		t.Fatalf("AddBooking: %v", err)
+	}
+	event.Version = 1
+	f := &notifierFixture{t: t, storage: s, queue: queue, notifier: notifier, event: event}
+	f.deliverAll()
+	if kinds := f.sent(); len(kinds) != 1 || kinds[0] != KindBookingCreated {
+		t.Fatalf("emails after booking = %v, want [%s]", kinds, KindBookingCreated)
+	}
+	return f
+}
+
+// deliverAll отдаёт Notifier все сообщения outbox по порядку и подтверждает их, как диспетчер.
+func (f *notifierFixture) deliverAll() {
+	f.t.Helper()
+	ctx := context.Background()
+	for {
+		messages, err := f.storage.ClaimOutbox(ctx, 100, time.Minute)
+		if err != nil {
+			f.t.Fatalf("ClaimOutbox: %v", err)
+		}
+		if len(messages) == 0 {
+			return
+		}
+		for _, msg := range messages {
+			if err := f.notifier.Send(ctx, msg); err != nil {
+				f.t.Fatalf("Send(%s): %v", msg.Type, err)
+			}
+			if err := f.storage.AckOutbox(ctx, msg.ID); err != nil {
+				f.t.Fatalf("AckOutbox: %v", err)
+			}
+		}
+	}
+}
+
+// sent забирает из очереди поставленные письма и возвращает их виды.
+func (f *notifierFixture) sent() []string {
+	var kinds []string
+	for {
+		select {
+		case j := <-f.queue.jobs:
+			kinds = append(kinds, j.email.Kind)
+		default:
+			return kinds
+		}
+	}
+}
+
+func (f *notifierFixture) update(change func(*models.Event)) {
+	f.t.Helper()
+	change(&f.event)
+	if err := f.storage.UpdateEvent(context.Background(), f.event); err != nil {
+		f.t.Fatalf("UpdateEvent: %v", err)
+	}
+	f.event.Version++
+}
+
+func TestNotifierEventChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*models.Event)
+		emails int
+	}{
+		{"title", func(e *models.Event) { e.Title = "Go meetup #2" }, 0},
+		{"capacity", func(e *models.Event) { e.Capacity = 20 }, 0},
+		{"start", func(e *models.Event) { e.StartAt = e.StartAt.Add(time.Hour) }, 1},
+		{"end", func(e *models.Event) { e.EndAt = e.EndAt.Add(time.Hour) }, 1},
+		{"time zone", func(e *models.Event) { e.TimeZone = "Europe/Moscow" }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newNotifierFixture(t)
+			f.update(tt.change)
+			f.deliverAll()
+			kinds := f.sent()
+			if len(kinds) != tt.emails {
+				t.Fatalf("emails = %v, want %d", kinds, tt.emails)
+			}
+			for _, kind := range kinds {
+				if kind != KindEventChanged {
+					t.Errorf("email kind = %s, want %s", kind, KindEventChanged)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifierSendsOncePerMessage(t *testing.T) {
+	ctx := context.Background()
+	f := newNotifierFixture(t)
+	f.update(func(e *models.Event) { e.StartAt = e.StartAt.Add(time.Hour) })
+
+	// EventUpdated, затем EventRescheduled того же агрегата
+	var rescheduled models.OutboxMessage
+	for rescheduled.ID == 0 {
+		messages, err := f.storage.ClaimOutbox(ctx, 100, time.Minute)
+		if err != nil || len(messages) == 0 {
+			t.Fatalf("ClaimOutbox = %d messages, %v", len(messages), err)
+		}
+		for _, msg := range messages {
+			if msg.Type == bus.EventRescheduled {
+				rescheduled = msg
+				continue
+			}
+			if err := f.storage.AckOutbox(ctx, msg.ID); err != nil {
+				t.Fatalf("AckOutbox: %v", err)
+			}
+		}
+	}
+
+	// повтор сообщения — например, после сбоя другого получателя или перезапуска
+	for range 3 {
+		if err := f.notifier.Send(ctx, rescheduled); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if kinds := f.sent(); len(kinds) != 1 {
+		t.Errorf("emails after repeated message = %v, want one", kinds)
+	}
+
+	// подтверждённое сообщение другим инстансом — не ошибка
+	if err := f.storage.AckOutbox(ctx, rescheduled.ID); err != nil {
+		t.Fatalf("AckOutbox: %v", err)
+	}
+	if err := f.notifier.Send(ctx, rescheduled); err != nil {
+		t.Errorf("Send after ack: %v", err)
+	}
+	if kinds := f.sent(); len(kinds) != 0 {
+		t.Errorf("emails after ack = %v, want none", kinds)
+	}
+}
+
+func TestNotifierReleasesMarkWhenQueueIsFull(t *testing.T) {
+	ctx := context.Background()
+	f := newNotifierFixture(t)
+	f.update(func(e *models.Event) { e.StartAt = e.StartAt.Add(time.Hour) })
+	for range cap(f.queue.jobs) {
+		if err := f.queue.Enqueue(Email{Kind: "filler"}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	messages, err := f.storage.ClaimOutbox(ctx, 100, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox: %v", err)
+	}
+	f.storage.AckOutbox(ctx, messages[0].ID) // EventUpdated
+	messages, _ = f.storage.ClaimOutbox(ctx, 100, time.Minute)
+	if len(messages) != 1 || messages[0].Type != bus.EventRescheduled {
+		t.Fatalf("ClaimOutbox = %v, want EventRescheduled", messages)
+	}
+	if err := f.notifier.Send(ctx, messages[0]); err != ErrQueueFull {
+		t.Fatalf("Send = %v, want %v", err, ErrQueueFull)
+	}
+
+	// после освобождения очереди повтор сообщения ставит письмо
+	f.sent()
+	if err := f.notifier.Send(ctx, messages[0]); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if kinds := f.sent(); len(kinds) != 1 || kinds[0] != KindEventChanged {
+		t.Errorf("emails = %v, want [%s]", kinds, KindEventChanged)
+	}
+}
@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/retry"
+)
+
+var (
+	// ErrQueueFull — в очереди нет места для всех писем; отправитель outbox повторит сообщение позже.
+	ErrQueueFull = errors.New("notification queue is full")
+	// ErrQueueClosed — очередь остановлена.
+	ErrQueueClosed = errors.New("notification queue is closed")
+)
+
+// job — письмо и число неудачных попыток его отправки.
+type job struct {
+	email    Email
+	attempts int
+}
+
+// Queue отправляет письма в фоне, не больше workers одновременно. Неудачная отправка повторяется
+// с экспоненциальной задержкой до max_attempts раз; ошибки Permanent не повторяются.
+// Очередь живёт в памяти процесса: источником правды остаётся outbox, а письма, не отправленные
+// к остановке, теряются и попадают в лог.
+type Queue struct {
+	sender Sender
+	cfg    config.Notifications
+	log    *slog.Logger
+
+	mu      sync.Mutex
+	jobs    chan job
+	delayed map[*time.Timer]job // ждут повтора
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func NewQueue(sender Sender, cfg config.Notifications, log *slog.Logger) *Queue {
+	return &Queue{
+		sender:  sender,
+		cfg:     cfg,
+		log:     log.With(slog.String("component", "notify")),
+		jobs:    make(chan job, max(cfg.QueueSize, 1)),
+		delayed: make(map[*time.Timer]job),
+	}
+}
+
+// Enqueue ставит письма в очередь: все или ни одного, чтобы повтор сообщения outbox
+// не отправил часть писем дважды.
+func (q *Queue) Enqueue(emails ...Email) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	// пишут в канал только под q.mu, поэтому свободное место не уменьшится до конца цикла
+	if cap(q.jobs)-len(q.jobs) < len(emails) {
+		return ErrQueueFull
+	}
+	for _, email := range emails {
+		q.jobs <- job{email: email}
+	}
+	return nil
+}
+
+// Start запускает воркеры. Остановить их — Stop.
+func (q *Queue) Start() {
+	for range max(q.cfg.Workers, 1) {
+		q.wg.Add(1)
+		go q.run()
+	}
+}
+
+// Stop перестаёт принимать письма, делает ещё одну попытку для всех ожидающих, включая отложенные
+// повторы, и ждёт её, но не дольше ctx.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for timer, j := range q.delayed {
+			if !timer.Stop() {
+				continue // уже сработал и сам вернёт письмо в очередь или запишет потерю
+			}
+			delete(q.delayed, timer)
+			select {
+			case q.jobs <- j:
+			default:
+				q.lost(j, ErrQueueFull)
+			}
+		}
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		q.send(j)
+	}
+}
+
+// send делает одну попытку и при временной ошибке откладывает повтор.
+func (q *Queue) send(j job) {
+	log := q.log.With(slog.String("kind", j.email.Kind), slog.String("to", j.email.To))
+	err := q.sender.Send(context.Background(), q.cfg.From, j.email)
+	if err == nil {
+		log.Debug("email sent", slog.Int("attempt", j.attempts+1))
+		return
+	}
+	j.attempts++
+	var permanent permanentError
+	if errors.As(err, &permanent) || j.attempts >= q.cfg.MaxAttempts {
+		log.Error("email dropped", slog.Int("attempts", j.attempts), sl.Err(err))
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		q.lost(j, err) // это и была последняя попытка при остановке
+		return
+	}
+	delay := retry.Backoff(j.attempts-1, q.cfg.MinBackoff, q.cfg.MaxBackoff)
+	log.Warn("email sending failed", slog.Int("attempt", j.attempts), slog.Duration("retry_in", delay), sl.Err(err))
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		// timer читаем под q.mu: send присваивает его, не отпуская блокировку
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.retry(timer)
+	})
+	q.delayed[timer] = j
+}
+
+// retry возвращает отложенное письмо в очередь. Вызывать под q.mu.
+func (q *Queue) retry(timer *time.Timer) {
+	j, ok := q.delayed[timer]
+	if !ok {
+		return // забрал Stop
+	}
+	delete(q.delayed, timer)
+	if q.closed {
+		q.lost(j, ErrQueueClosed)
+		return
+	}
+	select {
+	case q.jobs <- j:
+	default:
+		q.lost(j, ErrQueueFull)
+	}
+}
+
+// lost записывает в лог письмо, которое уже не будет отправлено.
+func (q *Queue) lost(j job, err error) {
+	q.log.Error("email lost", slog.String("kind", j.email.Kind), slog.String("to", j.email.To),
+		slog.Int("attempts", j.attempts), sl.Err(err))
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"TRYREST/internal/config"
+)
+
+// SMTPSender отправляет письма через SMTP-сервер: на каждое письмо — своё соединение.
+// Без implicit_tls шифрование включается через STARTTLS, если сервер его предлагает;
+// локальные заглушки вроде Mailpit или MailHog принимают письма и без него.
+type SMTPSender struct {
+	cfg config.SMTP
+}
+
+func NewSMTPSender(cfg config.SMTP) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, from string, email Email) error {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return Permanent(fmt.Errorf("parse from address: %w", err))
+	}
+	data, err := email.Bytes(from)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := net.Dialer{Timeout: s.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	// один предел на весь диалог с сервером
+	if s.cfg.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+	}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	if s.cfg.ImplicitTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !s.cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth сам отказывается передавать пароль без TLS, кроме как на localhost
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return classifySMTP(fmt.Errorf("auth: %w", err))
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return classifySMTP(fmt.Errorf("mail from: %w", err))
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return classifySMTP(fmt.Errorf("rcpt to: %w", err))
+	}
+	w, err := client.Data()
+	if err != nil {
+		return classifySMTP(fmt.Errorf("data: %w", err))
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return classifySMTP(fmt.Errorf("data: %w", err))
+	}
+	// письмо уже принято: ошибка QUIT на доставку не влияет
+	client.Quit()
+	return nil
+}
+
+// classifySMTP помечает постоянными ответы 5xx: сервер отверг письмо, и повтор получит тот же ответ.
+func classifySMTP(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,286 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/models"
+)
+
+// smtpServer — SMTP-сервер на net.Listener для тестов: принимает письма без TLS и авторизации,
+// отвечает на RCPT кодами из rcptReplies по очереди, а когда они закончатся — 250.
+type smtpServer struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	rcptReplies []string
+	rcpts       int
+	messages    []smtpMessage
+	received    chan struct{}
+}
+
+// smtpMessage — принятое письмо: конверт и данные после DATA.
+type smtpMessage struct {
+	from, to string
+	data     []byte
+}
+
+func newSMTPServer(t *testing.T, rcptReplies ...string) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpServer{ln: ln, rcptReplies: rcptReplies, received: make(chan struct{}, 10)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return // листенер закрыт
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config — настройки SMTPSender для этого сервера.
+func (s *smtpServer) config() config.SMTP {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	return config.SMTP{Host: addr.IP.String(), Port: addr.Port, Timeout: 5 * time.Second}
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var msg smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return // клиент закрыл соединение без QUIT после ошибки
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 fake")
+		case "MAIL":
+			msg = smtpMessage{from: arg}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts++
+			reply := "250 OK"
+			if len(s.rcptReplies) > 0 {
+				reply, s.rcptReplies = s.rcptReplies[0], s.rcptReplies[1:]
+			}
+			s.mu.Unlock()
+			msg.to = arg
+			tp.PrintfLine("%s", reply)
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			if msg.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+			s.received <- struct{}{}
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// stats возвращает число команд RCPT и принятые письма.
+func (s *smtpServer) stats() (int, []smtpMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rcpts, append([]smtpMessage(nil), s.messages...)
+}
+
+// testEmail собирает письмо о бронировании из встроенных шаблонов на языке locale.
+func testEmail(t *testing.T, locale string) Email {
+	t.Helper()
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	start := time.Date(2030, 3, 1, 15, 0, 0, 0, time.UTC)
+	email, err := templates.Render(KindBookingCreated, locale, Data{
+		User:    models.User{Name: "Анна", Email: "ann@example.com"},
+		Event:   models.Event{Title: "Go meetup", StartAt: start, EndAt: start.Add(2 * time.Hour), TimeZone: "Europe/Moscow"},
+		Booking: models.Booking{ID: 7, Status: models.BookingPending},
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	return email
+}
+
+func TestSMTPSenderMIME(t *testing.T) {
+	const from = "Booker <noreply@booker.local>"
+	tests := []struct {
+		locale  string
+		subject string
+		text    string
+	}{
+		{"ru", "Бронирование на «Go meetup»", "Начало: 01.03.2030 18:00 (Europe/Moscow)"},
+		{"en", `Your booking for "Go meetup"`, "Starts: Mar 1, 2030 6:00 PM (Europe/Moscow)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			srv := newSMTPServer(t)
+			email := testEmail(t, tt.locale)
+			if err := NewSMTPSender(srv.config()).Send(context.Background(), from, email); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			_, messages := srv.stats()
+			if len(messages) != 1 {
+				t.Fatalf("messages = %d, want 1", len(messages))
+			}
+			got := messages[0]
+			if got.from != "FROM:<noreply@booker.local>" || got.to != "TO:<ann@example.com>" {
+				t.Errorf("envelope = %s %s", got.from, got.to)
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(string(got.data)))
+			if err != nil {
+				t.Fatalf("ReadMessage: %v", err)
+			}
+			for key, want := range map[string]string{"From": from, "To": email.To, "MIME-Version": "1.0"} {
+				if value := msg.Header.Get(key); value != want {
+					t.Errorf("%s = %q, want %q", key, value, want)
+				}
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != tt.subject {
+				t.Errorf("Subject = %q (%v), want %q", subject, err, tt.subject)
+			}
+			if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@booker.local>") {
+				t.Errorf("Message-ID = %q, want it in the sender's domain", id)
+			}
+			if _, err := msg.Header.Date(); err != nil {
+				t.Errorf("Date: %v", err)
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/alternative" {
+				t.Fatalf("Content-Type = %q (%v), want multipart/alternative", msg.Header.Get("Content-Type"), err)
+			}
+			parts := multipart.NewReader(msg.Body, params["boundary"])
+			for _, want := range []struct{ contentType, body string }{
+				{"text/plain; charset=utf-8", email.Text},
+				{"text/html; charset=utf-8", email.HTML},
+			} {
+				part, err := parts.NextPart() // снимает quoted-printable
+				if err != nil {
+					t.Fatalf("NextPart: %v", err)
+				}
+				if ct := part.Header.Get("Content-Type"); ct != want.contentType {
+					t.Errorf("part Content-Type = %q, want %q", ct, want.contentType)
+				}
+				body, err := io.ReadAll(part)
+				if err != nil {
+					t.Fatalf("read part: %v", err)
+				}
+				if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want.body {
+					t.Errorf("%s part = %q, want %q", want.contentType, got, want.body)
+				}
+			}
+			if !strings.Contains(email.Text, tt.text) {
+				t.Errorf("text = %q, want it to contain %q", email.Text, tt.text)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderClassifiesReplies(t *testing.T) {
+	tests := []struct {
+		reply     string
+		permanent bool
+	}{
+		{"450 4.2.1 mailbox busy", false},
+		{"451 4.3.0 try again later", false},
+		{"550 5.1.1 no such user", true},
+		{"554 5.7.1 rejected", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.reply)
+			err := NewSMTPSender(srv.config()).Send(context.Background(), "noreply@booker.local", testEmail(t, "en"))
+			if err == nil {
+				t.Fatal("Send: want an error")
+			}
+			var permanent permanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("Send = %v, permanent = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestQueueRetriesTemporarySMTPErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		replies   []string
+		rcpts     int
+		delivered bool
+	}{
+		{"4xx retried", []string{"451 4.3.0 try again later", "421 4.7.0 too busy"}, 3, true},
+		{"5xx dropped", []string{"550 5.1.1 no such user"}, 1, false},
+		{"attempts exhausted", []string{"451 4.3.0", "451 4.3.0", "451 4.3.0", "451 4.3.0"}, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.replies...)
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg := config.Notifications{
+				From: "noreply@booker.local", QueueSize: 10, Workers: 1, MaxAttempts: 3,
+				MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond,
+			}
+			queue := NewQueue(NewSMTPSender(srv.config()), cfg, log)
+			queue.Start()
+			if err := queue.Enqueue(testEmail(t, "en")); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			// повторы идут через миллисекунды: за это время очередь либо доставит письмо, либо сдастся
+			select {
+			case <-srv.received:
+			case <-time.After(200 * time.Millisecond):
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := queue.Stop(ctx); err != nil {
+				t.Fatalf("Stop: %v", err)
+			}
+
+			rcpts, messages := srv.stats()
+			if rcpts != tt.rcpts {
+				t.Errorf("attempts = %d, want %d", rcpts, tt.rcpts)
+			}
+			if delivered := len(messages) == 1; delivered != tt.delivered {
+				t.Errorf("delivered = %v, want %v", delivered, tt.delivered)
+			}
+		})
+	}
+}
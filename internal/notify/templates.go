@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"TRYREST/internal/models"
+)
+
+// Виды писем — имена шаблонов в templates/.
+const (
+	KindBookingCreated   = "booking_created"
+	KindBookingCancelled = "booking_cancelled"
+	KindEventChanged     = "event_changed"
+	KindEventReminder    = "event_reminder"
+)
+
+// Kinds — все виды писем; для каждого и каждой локали должны быть .txt и .html.
+var Kinds = []string{KindBookingCreated, KindBookingCancelled, KindEventChanged, KindEventReminder}
+
+// Locales — поддерживаемые языки писем.
+var Locales = []string{"ru", "en"}
+
+// dateLayouts — формат даты начала и окончания события в письме.
+var dateLayouts = map[string]string{
+	"ru": "02.01.2006 15:04",
+	"en": "Jan 2, 2006 3:04 PM",
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Data — то, что видят шаблоны.
+type Data struct {
+	User    models.User
+	Event   models.Event
+	Booking models.Booking
+	// Start и End — время события в его часовом поясе, отформатированное для локали
+	Start  string
+	End    string
+	Locale string
+}
+
+// Templates — разобранные шаблоны писем. Текстовый шаблон <kind>.<locale>.txt задаёт тему блоком
+// "subject", остальное — текст письма; HTML-шаблон <kind>.<locale>.html задаёт блоки "title" и "body"
+// внутри общего layout.html.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates разбирает встроенные шаблоны; отсутствующий или сломанный шаблон — ошибка при старте,
+// а не при первой отправке.
+func LoadTemplates() (*Templates, error) {
+	layout, err := htmltemplate.ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		return nil, fmt.Errorf("parse layout: %w", err)
+	}
+	t := &Templates{text: make(map[string]*texttemplate.Template), html: make(map[string]*htmltemplate.Template)}
+	for _, kind := range Kinds {
+		for _, locale := range Locales {
+			name := kind + "." + locale
+			text, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("parse template %s: %w", name, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("template %s.txt has no subject block", name)
+			}
+			html, err := htmltemplate.Must(layout.Clone()).ParseFS(templateFS, "templates/"+name+".html")
+			if err != nil {
+				return nil, fmt.Errorf("parse template %s: %w", name, err)
+			}
+			t.text[name], t.html[name] = text, html
+		}
+	}
+	return t, nil
+}
+
+// Render собирает письмо вида kind на языке locale. Адресат — data.User.
+func (t *Templates) Render(kind, locale string, data Data) (Email, error) {
+	name := kind + "." + locale
+	text, ok := t.text[name]
+	if !ok {
+		return Email{}, fmt.Errorf("no template %s", name)
+	}
+	data.Locale = locale
+	data.Start, data.End = formatTime(data.Event, data.Event.StartAt, locale), formatTime(data.Event, data.Event.EndAt, locale)
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Email{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Email{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Email{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+	return Email{
+		To:      data.User.Email,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+		Kind:    kind,
+	}, nil
+}
+
+// formatTime показывает время в часовом поясе события и дописывает название пояса.
+func formatTime(event models.Event, at time.Time, locale string) string {
+	zone := "UTC"
+	if loc, err := time.LoadLocation(event.TimeZone); err == nil && event.TimeZone != "" {
+		at, zone = at.In(loc), event.TimeZone
+	} else {
+		at = at.UTC()
+	}
+	return fmt.Sprintf("%s (%s)", at.Format(dateLayouts[locale]), zone)
+}
+
+// knownLocale сообщает, есть ли шаблоны на языке locale.
+func knownLocale(locale string) bool {
+	return slices.Contains(Locales, locale)
+}
@@ -0,0 +1,119 @@
+// Package outbox — доставка доменных событий из таблицы outbox во внешние получатели.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/lib/retry"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Dispatcher раз в poll_interval забирает сообщения через storage.OutboxRepository и отдаёт их
+// всем sink'ам по очереди. Сообщение удаляется, только когда его приняли все; иначе оно
+// откладывается с экспоненциальной задержкой и следующие сообщения того же агрегата ждут его.
+// После max_attempts неудачных попыток сообщение помечается failed и очередь агрегата идёт дальше.
+type Dispatcher struct {
+	storage storage.OutboxRepository
+	cfg     config.Outbox
+	log     *slog.Logger
+	sinks   []namedSink
+	runner  loop.Runner
+}
+
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+func NewDispatcher(storage storage.OutboxRepository, cfg config.Outbox, log *slog.Logger) *Dispatcher {
+	return &Dispatcher{storage: storage, cfg: cfg, log: log.With(slog.String("component", "outbox"))}
+}
+
+// AddSink добавляет получателя; name попадает в логи и в last_error. Вызывать до Start.
+func (d *Dispatcher) AddSink(name string, sink Sink) {
+	d.sinks = append(d.sinks, namedSink{name: name, sink: sink})
+}
+
+// Start запускает фоновую горутину. Остановить её — Stop. При poll_interval <= 0 доставка выключена,
+// и сообщения копятся в outbox до включения.
+func (d *Dispatcher) Start() {
+	if d.cfg.PollInterval <= 0 {
+		d.log.Warn("outbox dispatcher disabled", slog.Duration("interval", d.cfg.PollInterval))
+		return
+	}
+	// после подтверждения открываются следующие сообщения тех же агрегатов — забираем сразу
+	d.runner.Start(d.cfg.PollInterval, d.dispatch)
+}
+
+// Stop останавливает горутину и ждёт, пока она доставит текущее сообщение, но не дольше ctx.
+// Забранные, но не доставленные сообщения вернутся в очередь по истечении lease.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	return d.runner.Stop(ctx)
+}
+
+// dispatch доставляет одну пачку и сообщает, было ли что-то подтверждено.
+func (d *Dispatcher) dispatch(ctx context.Context) bool {
+	// текущее сообщение доставляем до конца: Stop дожидается его
+	work := context.WithoutCancel(ctx)
+	messages, err := d.storage.ClaimOutbox(work, d.cfg.BatchSize, d.cfg.Lease)
+	if err != nil {
+		d.log.Error("failed to claim outbox messages", sl.Err(err))
+		return false
+	}
+	acked := false
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			return false
+		}
+		if err := d.deliver(work, msg); err != nil {
+			if d.cfg.MaxAttempts > 0 && msg.Attempts+1 >= d.cfg.MaxAttempts {
+				d.fail(work, msg, err)
+				continue
+			}
+			next := time.Now().Add(retry.Backoff(msg.Attempts, d.cfg.MinBackoff, d.cfg.MaxBackoff))
+			d.log.Warn("outbox delivery failed",
+				slog.Int64("id", msg.ID), slog.String("type", msg.Type),
+				slog.Int("attempt", msg.Attempts+1), slog.Time("next_attempt_at", next), sl.Err(err))
+			if err := d.storage.RetryOutbox(work, msg.ID, next, err.Error()); err != nil {
+				d.log.Error("failed to reschedule outbox message", slog.Int64("id", msg.ID), sl.Err(err))
+			}
+			continue
+		}
+		if err := d.storage.AckOutbox(work, msg.ID); err != nil {
+			// сообщение вернётся после lease и будет доставлено повторно
+			d.log.Error("failed to ack outbox message", slog.Int64("id", msg.ID), sl.Err(err))
+			continue
+		}
+		acked = true
+	}
+	return acked
+}
+
+// fail помечает сообщение недоставляемым: получатели его уже не увидят, поэтому это ошибка, а не предупреждение.
+func (d *Dispatcher) fail(ctx context.Context, msg models.OutboxMessage, err error) {
+	log := d.log.With(slog.Int64("id", msg.ID), slog.String("type", msg.Type),
+		slog.String("aggregate_type", msg.AggregateType), slog.Int64("aggregate_id", msg.AggregateID))
+	if ferr := d.storage.FailOutbox(ctx, msg.ID, err.Error()); ferr != nil {
+		// сообщение вернётся после lease и получит ещё одну попытку
+		log.Error("failed to mark outbox message as failed", sl.Err(ferr))
+		return
+	}
+	log.Error("outbox message dropped after max attempts", slog.Int("attempts", msg.Attempts+1), sl.Err(err))
+}
+
+// deliver отдаёт сообщение всем sink'ам; первая ошибка прерывает доставку.
+func (d *Dispatcher) deliver(ctx context.Context, msg models.OutboxMessage) error {
+	for _, s := range d.sinks {
+		if err := s.sink.Send(ctx, msg); err != nil {
+			return fmt.Errorf("sink %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/config"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage/memory"
+)
+
+// sinkFunc — получатель из функции.
+type sinkFunc func(ctx context.Context, msg models.OutboxMessage) error
+
+func (f sinkFunc) Send(ctx context.Context, msg models.OutboxMessage) error { return f(ctx, msg) }
+
+func TestDispatcherFailsMessageAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.New(log, bus.New())
+
+	// два сообщения одного агрегата: UserCreated и UserUpdated
+	id, err := s.AddUser(ctx, models.User{Name: "Ann", Email: "ann@example.com", Role: models.RoleAttendee})
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	if err := s.UpdateUser(ctx, models.User{ID: id, Name: "Anna", Email: "ann@example.com"}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	var delivered []string
+	d := NewDispatcher(s, config.Outbox{BatchSize: 10, Lease: time.Minute, MaxAttempts: 2}, log)
+	d.AddSink("test", sinkFunc(func(ctx context.Context, msg models.OutboxMessage) error {
+		if msg.Type == bus.UserCreated {
+			return errors.New("sink is down")
+		}
+		delivered = append(delivered, msg.Type)
+		return nil
+	}))
+
+	// первая попытка — повтор без задержки: min_backoff нулевой
+	d.dispatch(ctx)
+	if len(delivered) != 0 {
+		t.Fatalf("delivered %v while the earlier message is pending", delivered)
+	}
+	// вторая попытка последняя: сообщение помечается failed, следующее сообщение агрегата открывается
+	d.dispatch(ctx)
+	d.dispatch(ctx)
+	if len(delivered) != 1 || delivered[0] != bus.UserUpdated {
+		t.Fatalf("delivered = %v, want [%s]", delivered, bus.UserUpdated)
+	}
+
+	// failed-сообщение больше не выдаётся
+	messages, err := s.ClaimOutbox(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("ClaimOutbox returned %d messages after failure, want 0", len(messages))
+	}
+}
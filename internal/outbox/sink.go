@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+
+	"TRYREST/internal/models"
+)
+
+// Sink — получатель доменных событий. Доставка «хотя бы один раз»: после сбоя сообщение придёт
+// повторно, в том числе в sink, который его уже принял, поэтому повторы отсеивают по msg.ID.
+// Сообщения одного агрегата приходят по порядку и не параллельно.
+type Sink interface {
+	Send(ctx context.Context, msg models.OutboxMessage) error
+}
+
+// LogSink пишет каждое сообщение в лог — для отладки и как журнал доменных событий.
+type LogSink struct {
+	log *slog.Logger
+}
+
+func NewLogSink(log *slog.Logger) *LogSink {
+	return &LogSink{log: log}
+}
+
+func (s *LogSink) Send(ctx context.Context, msg models.OutboxMessage) error {
+	s.log.InfoContext(ctx, "domain event",
+		slog.Int64("id", msg.ID),
+		slog.String("type", msg.Type),
+		slog.String("aggregate_type", msg.AggregateType),
+		slog.Int64("aggregate_id", msg.AggregateID),
+		slog.String("payload", string(msg.Payload)),
+	)
+	return nil
+}
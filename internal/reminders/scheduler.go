@@ -0,0 +1,118 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Repository — то, что нужно Scheduler из хранилища.
+type Repository interface {
+	storage.ReminderRepository
+	GetBookingByID(ctx context.Context, id int64) (models.Booking, error)
+	GetEventByID(ctx context.Context, id int64) (models.Event, error)
+}
+
+// Notifier отправляет напоминание владельцу бронирования; реализуется notify.Notifier.
+type Notifier interface {
+	Remind(ctx context.Context, event models.Event, booking models.Booking) error
+}
+
+// Scheduler раз в poll_interval забирает наступившие напоминания и отправляет их через Notifier.
+// Напоминания хранятся в хранилище, поэтому переживают перезапуск, а аренда при выборке не даёт
+// нескольким инстансам отправить одно напоминание дважды. Неудачная отправка повторяется
+// по истечении аренды.
+type Scheduler struct {
+	storage  Repository
+	notifier Notifier
+	cfg      config.Reminders
+	log      *slog.Logger
+	runner   loop.Runner
+}
+
+func NewScheduler(storage Repository, notifier Notifier, cfg config.Reminders, log *slog.Logger) *Scheduler {
+	return &Scheduler{storage: storage, notifier: notifier, cfg: cfg, log: log.With(slog.String("component", "reminders"))}
+}
+
+// Start запускает фоновую горутину. Остановить её — Stop. При poll_interval <= 0 отправка выключена.
+func (s *Scheduler) Start() {
+	if s.cfg.PollInterval <= 0 {
+		s.log.Warn("reminder scheduler disabled", slog.Duration("interval", s.cfg.PollInterval))
+		return
+	}
+	s.runner.Start(s.cfg.PollInterval, s.poll)
+}
+
+// Stop останавливает горутину и ждёт текущую пачку, но не дольше ctx.
+// Забранные, но не отправленные напоминания вернутся по истечении lease.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	return s.runner.Stop(ctx)
+}
+
+// poll отправляет одну пачку напоминаний и сообщает, была ли она полной.
+func (s *Scheduler) poll(ctx context.Context) bool {
+	// начатую пачку доводим до конца: Stop дожидается её
+	work := context.WithoutCancel(ctx)
+	reminders, err := s.storage.ClaimReminders(work, s.cfg.BatchSize, s.cfg.Lease)
+	if err != nil {
+		s.log.Error("failed to claim reminders", sl.Err(err))
+		return false
+	}
+	for _, reminder := range reminders {
+		if ctx.Err() != nil {
+			break
+		}
+		s.remind(work, reminder)
+	}
+	return len(reminders) == s.cfg.BatchSize
+}
+
+// remind отправляет одно напоминание и отмечает его отправленным.
+func (s *Scheduler) remind(ctx context.Context, reminder models.Reminder) {
+	log := s.log.With(slog.Int64("reminder_id", reminder.ID), slog.Int64("booking_id", reminder.BookingID),
+		slog.Duration("offset", reminder.Offset))
+	booking, err := s.storage.GetBookingByID(ctx, reminder.BookingID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return // бронирование удалено вместе с напоминаниями
+	}
+	if err != nil {
+		log.Error("failed to load booking", sl.Err(err))
+		return
+	}
+	// отменили после выборки, а сообщение outbox об отмене ещё в пути
+	if !booking.Active() {
+		if _, err := s.storage.CancelReminders(ctx, booking.ID); err != nil {
+			log.Error("failed to cancel reminders", sl.Err(err))
+		}
+		return
+	}
+	event, err := s.storage.GetEventByID(ctx, booking.EventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Error("failed to load event", sl.Err(err))
+		return
+	}
+
+	if err := s.notifier.Remind(ctx, event, booking); err != nil {
+		log.Warn("failed to send reminder, will retry after lease", slog.Duration("lease", s.cfg.Lease), sl.Err(err))
+		return
+	}
+	err = s.storage.CompleteReminder(ctx, reminder.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return // отменено, пока письмо собиралось
+	}
+	if err != nil {
+		// напоминание вернётся после lease и уйдёт повторно
+		log.Error("failed to record reminder", sl.Err(err))
+		return
+	}
+	log.Info("reminder sent")
+}
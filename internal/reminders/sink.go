@@ -0,0 +1,51 @@
+// Package reminders — напоминания участникам перед началом события: планирование по сообщениям outbox
+// и фоновая отправка, согласованная между инстансами через хранилище.
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Sink — получатель outbox: создаёт напоминания новым бронированиям, переносит их вслед за событием
+// и отменяет при отмене бронирования. Повтор сообщения безопасен: хранилище не создаёт дублей.
+type Sink struct {
+	storage storage.ReminderRepository
+	offsets []time.Duration
+}
+
+// NewSink проверяет offsets: каждый не меньше секунды; повторы отбрасываются.
+func NewSink(storage storage.ReminderRepository, offsets []time.Duration) (*Sink, error) {
+	var normalized []time.Duration
+	for _, offset := range offsets {
+		if offset < time.Second {
+			return nil, fmt.Errorf("reminder offset %s must be at least 1s", offset)
+		}
+		offset = offset.Truncate(time.Second)
+		if !slices.Contains(normalized, offset) {
+			normalized = append(normalized, offset)
+		}
+	}
+	return &Sink{storage: storage, offsets: normalized}, nil
+}
+
+func (s *Sink) Send(ctx context.Context, msg models.OutboxMessage) error {
+	var err error
+	switch msg.Type {
+	// перенос на другое событие тоже пересчитывает сроки
+	case bus.BookingCreated, bus.BookingUpdated:
+		_, err = s.storage.ScheduleReminders(ctx, msg.AggregateID, s.offsets)
+	case bus.BookingCancelled, bus.BookingAttended:
+		_, err = s.storage.CancelReminders(ctx, msg.AggregateID)
+	case bus.EventUpdated:
+		_, err = s.storage.RescheduleEventReminders(ctx, msg.AggregateID, s.offsets)
+	}
+	// удаление бронирования или события удаляет напоминания каскадом
+	return err
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"time"
+
+	"TRYREST/internal/models"
+)
+
+// Page — параметры keyset-пагинации. Записи упорядочены по паре (поле Sort, id),
+// следующая страница начинается строго после ключа After.
+type Page struct {
+	Limit int    // 0 — без ограничения
+	Sort  string // поле сортировки, "" или "id" — только по id
+	Desc  bool
+	After *Cursor // nil — первая страница
+}
+
+// Cursor — ключ последней записи предыдущей страницы.
+type Cursor struct {
+	Value string // значение поля сортировки, см. UserKey/EventKey; пустое при сортировке по id
+	ID    int64
+}
+
+// Поля, по которым разрешена сортировка списков. Первое — сортировка по умолчанию.
+var (
+	UserSortFields     = []string{"id", "name", "email"}
+	EventSortFields    = []string{"start_at", "id", "title"}
+	BookingSortFields  = []string{"id"}
+	DeliverySortFields = []string{"id"}
+)
+
+// UserFilter — условия выборки пользователей.
+type UserFilter struct {
+	Email string // подстрока email без учёта регистра
+	Page
+}
+
+// EventFilter — условия выборки событий, нулевые поля не ограничивают выборку.
+type EventFilter struct {
+	From  time.Time // start_at >= From
+	To    time.Time // start_at < To
+	Title string    // подстрока названия без учёта регистра
+	Page
+}
+
+// BookingFilter — условия выборки бронирований, нулевые поля не ограничивают выборку.
+type BookingFilter struct {
+	UserID  int64
+	EventID int64
+	Status  string
+	Page
+}
+
+// WebhookDeliveryFilter — условия выборки доставок одной подписки.
+type WebhookDeliveryFilter struct {
+	WebhookID int64
+	Status    string // пустой — любой
+	Page
+}
+
+// timeKeyLayout — фиксированной ширины и в UTC, поэтому строки ключей сравниваются как моменты времени.
+const timeKeyLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// UserKey — ключ пользователя для курсора при сортировке по полю sort.
+func UserKey(user models.User, sort string) Cursor {
+	switch sort {
+	case "name":
+		return Cursor{Value: user.Name, ID: user.ID}
+	case "email":
+		return Cursor{Value: user.Email, ID: user.ID}
+	}
+	return Cursor{ID: user.ID}
+}
+
+// EventKey — ключ события для курсора при сортировке по полю sort.
+func EventKey(event models.Event, sort string) Cursor {
+	switch sort {
+	case "start_at":
+		return Cursor{Value: event.StartAt.UTC().Format(timeKeyLayout), ID: event.ID}
+	case "title":
+		return Cursor{Value: event.Title, ID: event.ID}
+	}
+	return Cursor{ID: event.ID}
+}
+
+// BookingKey — ключ бронирования для курсора.
+func BookingKey(booking models.Booking, _ string) Cursor {
+	return Cursor{ID: booking.ID}
+}
+
+// DeliveryKey — ключ доставки вебхука для курсора.
+func DeliveryKey(delivery models.WebhookDelivery, _ string) Cursor {
+	return Cursor{ID: delivery.ID}
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) AddHold(ctx context.Context, eventID, userID int64, ttl time.Duration) (models.Hold, error) {
+	const op = "storage.memory.AddHold"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventID]; !ok {
+		return models.Hold{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return models.Hold{}, fmt.Errorf("%s: user %d: %w", op, userID, storage.ErrForeignKey)
+	}
+	if err := s.checkSeat(eventID); err != nil {
+		return models.Hold{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+	for _, hold := range s.holds {
+		if hold.EventID == eventID && hold.UserID == userID && hold.ExpiresAt.After(now) {
+			return models.Hold{}, fmt.Errorf("%s: user %d already holds a seat on event %d: %w", op, userID, eventID, storage.ErrConflict)
+		}
+	}
+
+	s.lastHoldID++
+	hold := models.Hold{ID: s.lastHoldID, EventID: eventID, UserID: userID, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	s.holds[hold.ID] = hold
+	return hold, nil
+}
+
+func (s *Storage) GetHoldByID(ctx context.Context, id int64) (models.Hold, error) {
+	const op = "storage.memory.GetHoldByID"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hold, ok := s.holds[id]
+	if !ok {
+		return models.Hold{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return hold, nil
+}
+
+func (s *Storage) ConfirmHold(ctx context.Context, id int64) (models.Booking, error) {
+	const op = "storage.memory.ConfirmHold"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, ok := s.holds[id]
+	if !ok {
+		return models.Booking{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	now := time.Now()
+	if !hold.ExpiresAt.After(now) {
+		return models.Booking{}, fmt.Errorf("%s: hold %d: %w", op, id, storage.ErrHoldExpired)
+	}
+	if !s.events[hold.EventID].StartAt.After(now) {
+		return models.Booking{}, fmt.Errorf("%s: event %d: %w", op, hold.EventID, storage.ErrEventStarted)
+	}
+
+	// место уже учтено холдом, поэтому вместимость не проверяем
+	delete(s.holds, id)
+	s.lastBookingID++
+	booking := models.Booking{
+		ID:          s.lastBookingID,
+		EventID:     hold.EventID,
+		UserID:      hold.UserID,
+		Status:      models.BookingConfirmed,
+		CreatedAt:   now,
+		ConfirmedAt: &now,
+		Version:     1,
+	}
+	s.bookings[booking.ID] = booking
+	s.bookingOutbox(bus.BookingCreated, booking)
+	return booking, nil
+}
+
+func (s *Storage) ReleaseHold(ctx context.Context, id int64) error {
+	const op = "storage.memory.ReleaseHold"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, ok := s.holds[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	delete(s.holds, id)
+	promoted = s.promoteWaitlist(hold.EventID)
+	return nil
+}
+
+func (s *Storage) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	released := 0
+	events := make(map[int64]bool)
+	for id, hold := range s.holds {
+		if !hold.ExpiresAt.After(now) {
+			delete(s.holds, id)
+			events[hold.EventID] = true
+			released++
+		}
+	}
+	for eventID := range events {
+		promoted = append(promoted, s.promoteWaitlist(eventID)...)
+	}
+	return released, nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	keys := s.idempotency[rec.UserID]
+	if keys == nil {
+		keys = make(map[string]models.IdempotencyRecord)
+		s.idempotency[rec.UserID] = keys
+	}
+	// истёкший ключ занимаем заново; остальные истёкшие удаляет PurgeExpiredIdempotencyKeys
+	if existing, ok := keys[rec.Key]; ok && existing.ExpiresAt.After(now) {
+		return existing, false, nil
+	}
+	rec.StatusCode, rec.Header, rec.Body = 0, nil, nil
+	rec.CreatedAt = now
+	keys[rec.Key] = rec
+	return rec, true, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, userID int64, key string, statusCode int, header http.Header, body []byte) error {
+	const op = "storage.memory.CompleteIdempotencyKey"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.idempotency[userID][key]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	rec.StatusCode, rec.Header = statusCode, header.Clone()
+	rec.Body = append([]byte(nil), body...)
+	s.idempotency[userID][key] = rec
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, userID int64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteIdempotencyKey(userID, key)
+	return nil
+}
+
+func (s *Storage) PurgeExpiredIdempotencyKeys(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	purged := 0
+	for userID, keys := range s.idempotency {
+		for key, rec := range keys {
+			if !rec.ExpiresAt.After(now) {
+				s.deleteIdempotencyKey(userID, key)
+				purged++
+			}
+		}
+	}
+	return purged, nil
+}
+
+// deleteIdempotencyKey удаляет ключ и пустой индекс пользователя. Вызывать под s.mu.Lock.
+func (s *Storage) deleteIdempotencyKey(userID int64, key string) {
+	keys := s.idempotency[userID]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(s.idempotency, userID)
+	}
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+)
+
+func TestPurgeExpiredIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), bus.New())
+	now := time.Now()
+	keys := []models.IdempotencyRecord{
+		{UserID: 1, Key: "expired", ExpiresAt: now.Add(-time.Minute)},
+		{UserID: 1, Key: "live", ExpiresAt: now.Add(time.Hour)},
+		{UserID: 2, Key: "expired", ExpiresAt: now.Add(-time.Second)},
+	}
+	for _, rec := range keys {
+		if _, ok, err := s.ReserveIdempotencyKey(ctx, rec); err != nil || !ok {
+			t.Fatalf("ReserveIdempotencyKey(%d, %q) = %v, %v", rec.UserID, rec.Key, ok, err)
+		}
+	}
+
+	purged, err := s.PurgeExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredIdempotencyKeys: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("purged = %d, want 2", purged)
+	}
+	if _, ok := s.idempotency[2]; ok {
+		t.Error("index of user 2 is left empty instead of removed")
+	}
+	// живой ключ остался и по-прежнему занят
+	if _, ok, _ := s.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: 1, Key: "live", ExpiresAt: now.Add(time.Hour)}); ok {
+		t.Error("live key was purged")
+	}
+}
+
+func TestReserveReplacesExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), bus.New())
+	expired := models.IdempotencyRecord{UserID: 1, Key: "k", Fingerprint: "old", ExpiresAt: time.Now().Add(-time.Second)}
+	if _, ok, err := s.ReserveIdempotencyKey(ctx, expired); err != nil || !ok {
+		t.Fatalf("ReserveIdempotencyKey = %v, %v", ok, err)
+	}
+	fresh := models.IdempotencyRecord{UserID: 1, Key: "k", Fingerprint: "new", ExpiresAt: time.Now().Add(time.Hour)}
+	rec, ok, err := s.ReserveIdempotencyKey(ctx, fresh)
+	if err != nil || !ok {
+		t.Fatalf("ReserveIdempotencyKey over expired key = %v, %v, want reserved", ok, err)
+	}
+	if rec.Fingerprint != "new" {
+		t.Errorf("fingerprint = %q, want %q", rec.Fingerprint, "new")
+	}
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"TRYREST/internal/storage"
+)
+
+// paginate упорядочивает items по ключу (значение поля, id), отбрасывает всё до курсора
+// и обрезает результат до page.Limit — так же, как keyset-запрос в postgre.
+func paginate[T any](items []T, page storage.Page, key func(T, string) storage.Cursor) []T {
+	less := func(a, b storage.Cursor) bool {
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return a.ID < b.ID
+	}
+	if page.Desc {
+		asc := less
+		less = func(a, b storage.Cursor) bool { return asc(b, a) }
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return less(key(items[i], page.Sort), key(items[j], page.Sort))
+	})
+
+	if page.After != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			return less(*page.After, key(items[i], page.Sort))
+		})
+		items = items[start:]
+	}
+	if page.Limit > 0 && len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
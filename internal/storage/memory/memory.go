@@ -0,0 +1,568 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage — хранилище в памяти процесса с той же семантикой, что и postgre.Storage:
+// уникальный email, каскадное удаление бронирований, ошибки storage.ErrNotFound.
+// Безопасно для конкурентного использования.
+type Storage struct {
+	mu  sync.RWMutex
+	log *slog.Logger
+	bus bus.Publisher // может быть nil
+
+	users    map[int64]models.User
+	events   map[int64]models.Event
+	bookings map[int64]models.Booking
+	waitlist map[int64]models.WaitlistEntry
+	holds    map[int64]models.Hold
+
+	idempotency map[int64]map[string]models.IdempotencyRecord // ключи по пользователю
+	outbox      []outboxEntry                                 // по возрастанию ID
+	webhooks    map[int64]models.Webhook
+	deliveries  map[int64]deliveryEntry
+	reminders   map[int64]reminderEntry
+
+	// последние выданные идентификаторы, аналог IDENTITY в postgres
+	lastUserID     int64
+	lastEventID    int64
+	lastBookingID  int64
+	lastWaitlistID int64
+	lastHoldID     int64
+	lastOutboxID   int64
+	lastWebhookID  int64
+	lastDeliveryID int64
+	lastReminderID int64
+}
+
+// rolePermissions повторяет содержимое role_permissions из миграций 6_roles и 13_webhooks.
+var rolePermissions = map[string][]string{
+	models.RoleAdmin:     {"users:manage", "events:write", "events:write_any", "bookings:write", "bookings:write_any", "webhooks:manage"},
+	models.RoleOrganizer: {"events:write", "bookings:write"},
+	models.RoleAttendee:  {"bookings:write"},
+}
+
+func New(logger *slog.Logger, publisher bus.Publisher) *Storage {
+	return &Storage{
+		log:      logger,
+		bus:      publisher,
+		users:    make(map[int64]models.User),
+		events:   make(map[int64]models.Event),
+		bookings: make(map[int64]models.Booking),
+		waitlist: make(map[int64]models.WaitlistEntry),
+		holds:    make(map[int64]models.Hold),
+
+		idempotency: make(map[int64]map[string]models.IdempotencyRecord),
+		webhooks:    make(map[int64]models.Webhook),
+		deliveries:  make(map[int64]deliveryEntry),
+		reminders:   make(map[int64]reminderEntry),
+	}
+}
+
+func (s *Storage) GetAllUsers(ctx context.Context, filter storage.UserFilter) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []models.User
+	for _, user := range s.users {
+		if filter.Email != "" && !containsFold(user.Email, filter.Email) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return paginate(users, filter.Page, storage.UserKey), nil
+}
+
+func (s *Storage) GetUserByID(ctx context.Context, id int64) (models.User, error) {
+	const op = "storage.memory.GetUserByID"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return user, nil
+}
+
+func (s *Storage) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.memory.GetUserByEmail"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+}
+
+func (s *Storage) AddUser(ctx context.Context, user models.User) (int64, error) {
+	const op = "storage.memory.AddUser"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(user.Email, 0) {
+		return 0, fmt.Errorf("%s: email %q: %w", op, user.Email, storage.ErrConflict)
+	}
+	if user.Role == "" {
+		user.Role = models.RoleAttendee // DEFAULT 'attendee'
+	}
+	if _, ok := rolePermissions[user.Role]; !ok {
+		return 0, fmt.Errorf("%s: role %q: %w", op, user.Role, storage.ErrForeignKey)
+	}
+	s.lastUserID++
+	user.ID = s.lastUserID
+	user.Version = 1
+	s.users[user.ID] = user
+	s.userOutbox(bus.UserCreated, user)
+	return user.ID, nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, update models.User) error {
+	const op = "storage.memory.UpdateUser"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[update.ID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(user.Version, update.Version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if s.emailTaken(update.Email, update.ID) {
+		return fmt.Errorf("%s: email %q: %w", op, update.Email, storage.ErrConflict)
+	}
+	if update.Role != "" {
+		if _, ok := rolePermissions[update.Role]; !ok {
+			return fmt.Errorf("%s: role %q: %w", op, update.Role, storage.ErrForeignKey)
+		}
+		user.Role = update.Role
+	}
+	user.Name, user.Email = update.Name, update.Email
+	user.Version++
+	s.users[user.ID] = user
+	s.userOutbox(bus.UserUpdated, user)
+	return nil
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, id, version int64) error {
+	const op = "storage.memory.DeleteUser"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(user.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	delete(s.users, id)
+	s.userOutbox(bus.UserDeleted, user)
+	// места освобождаем явно, как postgre: BookingDeleted по каждому бронированию и продвижение очереди.
+	// Очередь пользователя удаляется первой, чтобы место не досталось ему самому
+	for entryID, entry := range s.waitlist {
+		if entry.UserID == id {
+			delete(s.waitlist, entryID)
+		}
+	}
+	events := make(map[int64]bool)
+	for _, bookingID := range slices.Sorted(maps.Keys(s.bookings)) {
+		booking := s.bookings[bookingID]
+		if booking.UserID != id {
+			continue
+		}
+		s.deleteBooking(booking)
+		if booking.Status != models.BookingCancelled {
+			events[booking.EventID] = true
+		}
+	}
+	now := time.Now()
+	for holdID, hold := range s.holds {
+		if hold.UserID == id {
+			delete(s.holds, holdID)
+			if hold.ExpiresAt.After(now) {
+				events[hold.EventID] = true
+			}
+		}
+	}
+	for _, eventID := range slices.Sorted(maps.Keys(events)) {
+		promoted = append(promoted, s.promoteWaitlist(eventID)...)
+	}
+	delete(s.idempotency, id)
+	// ON DELETE SET NULL
+	for eventID, event := range s.events {
+		if event.OrganizerID == id {
+			event.OrganizerID = 0
+			s.events[eventID] = event
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetAllEvents(ctx context.Context, filter storage.EventFilter) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.Event
+	for _, event := range s.events {
+		if !filter.From.IsZero() && event.StartAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !event.StartAt.Before(filter.To) {
+			continue
+		}
+		if filter.Title != "" && !containsFold(event.Title, filter.Title) {
+			continue
+		}
+		events = append(events, s.withRemainingSeats(event))
+	}
+	return paginate(events, filter.Page, storage.EventKey), nil
+}
+
+func (s *Storage) GetEventByID(ctx context.Context, id int64) (models.Event, error) {
+	const op = "storage.memory.GetEventByID"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return models.Event{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return s.withRemainingSeats(event), nil
+}
+
+func (s *Storage) AddEvent(ctx context.Context, event models.Event) (int64, error) {
+	const op = "storage.memory.AddEvent"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[event.OrganizerID]; event.OrganizerID != 0 && !ok {
+		return 0, fmt.Errorf("%s: organizer %d: %w", op, event.OrganizerID, storage.ErrForeignKey)
+	}
+	s.lastEventID++
+	event.ID = s.lastEventID
+	event.RemainingSeats = 0
+	event.Version = 1
+	s.events[event.ID] = event
+	s.eventOutbox(bus.EventCreated, event)
+	return event.ID, nil
+}
+
+func (s *Storage) UpdateEvent(ctx context.Context, event models.Event) error {
+	const op = "storage.memory.UpdateEvent"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }() // выполнится после Unlock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.events[event.ID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(current.Version, event.Version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if booked := s.bookedSeats(event.ID); event.Capacity < booked {
+		return fmt.Errorf("%s: capacity %d is less than %d booked seats: %w", op, event.Capacity, booked, storage.ErrConflict)
+	}
+	event.RemainingSeats = 0
+	event.OrganizerID = current.OrganizerID
+	event.Version = current.Version + 1
+	s.events[event.ID] = event
+	promoted = s.promoteWaitlist(event.ID)
+	s.eventUpdatedOutbox(current, event)
+	return nil
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, id, version int64) error {
+	const op = "storage.memory.DeleteEvent"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(event.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// снимок — до удаления, пока бронирования ещё на месте
+	s.eventOutbox(bus.EventDeleted, event)
+	delete(s.events, id)
+	// ON DELETE CASCADE
+	for bookingID, booking := range s.bookings {
+		if booking.EventID == id {
+			delete(s.bookings, bookingID)
+		}
+	}
+	for entryID, entry := range s.waitlist {
+		if entry.EventID == id {
+			delete(s.waitlist, entryID)
+		}
+	}
+	for holdID, hold := range s.holds {
+		if hold.EventID == id {
+			delete(s.holds, holdID)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetAllBookings(ctx context.Context, filter storage.BookingFilter) ([]models.Booking, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var bookings []models.Booking
+	for _, booking := range s.bookings {
+		if filter.UserID != 0 && booking.UserID != filter.UserID {
+			continue
+		}
+		if filter.EventID != 0 && booking.EventID != filter.EventID {
+			continue
+		}
+		if filter.Status != "" && booking.Status != filter.Status {
+			continue
+		}
+		bookings = append(bookings, booking)
+	}
+	return paginate(bookings, filter.Page, storage.BookingKey), nil
+}
+
+func (s *Storage) GetBookingByID(ctx context.Context, id int64) (models.Booking, error) {
+	const op = "storage.memory.GetBookingByID"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	booking, ok := s.bookings[id]
+	if !ok {
+		return models.Booking{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return booking, nil
+}
+
+func (s *Storage) AddBooking(ctx context.Context, eventID, userID int64) (int64, error) {
+	const op = "storage.memory.AddBooking"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkBookingRefs(eventID, userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.checkSeat(eventID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s.lastBookingID++
+	booking := models.Booking{
+		ID:        s.lastBookingID,
+		EventID:   eventID,
+		UserID:    userID,
+		Status:    models.BookingPending,
+		CreatedAt: time.Now(),
+		Version:   1,
+	}
+	s.bookings[booking.ID] = booking
+	s.bookingOutbox(bus.BookingCreated, booking)
+	return booking.ID, nil
+}
+
+func (s *Storage) UpdateBooking(ctx context.Context, id, eventID, userID, version int64) error {
+	const op = "storage.memory.UpdateBooking"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.bookings[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(current.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !current.Active() {
+		return fmt.Errorf("%s: booking %d is %s: %w", op, id, current.Status, storage.ErrInvalidStatus)
+	}
+	if err := s.checkBookingRefs(eventID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if current.EventID != eventID {
+		if err := s.checkSeat(eventID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	previousEventID := current.EventID
+	current.EventID, current.UserID = eventID, userID
+	current.Version++
+	s.bookings[id] = current
+	s.bookingOutbox(bus.BookingUpdated, current)
+	if previousEventID != eventID {
+		promoted = s.promoteWaitlist(previousEventID)
+	}
+	return nil
+}
+
+func (s *Storage) SetBookingStatus(ctx context.Context, id int64, status string) (models.Booking, error) {
+	const op = "storage.memory.SetBookingStatus"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, ok := s.bookings[id]
+	if !ok {
+		return models.Booking{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if !booking.CanTransitionTo(status) {
+		return models.Booking{}, fmt.Errorf("%s: booking %d: %s -> %s: %w", op, id, booking.Status, status, storage.ErrInvalidStatus)
+	}
+	now := time.Now()
+	switch status {
+	case models.BookingConfirmed:
+		booking.ConfirmedAt = &now
+	case models.BookingCancelled:
+		booking.CancelledAt = &now
+	case models.BookingAttended:
+		booking.AttendedAt = &now
+	}
+	booking.Status = status
+	booking.Version++
+	s.bookings[id] = booking
+	s.bookingOutbox(bookingStatusMessages[status], booking)
+	if status == models.BookingCancelled {
+		promoted = s.promoteWaitlist(booking.EventID)
+	}
+	return booking, nil
+}
+
+func (s *Storage) DeleteBooking(ctx context.Context, id, version int64) error {
+	const op = "storage.memory.DeleteBooking"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, ok := s.bookings[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(booking.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.deleteBooking(booking)
+	if booking.Status != models.BookingCancelled {
+		promoted = s.promoteWaitlist(booking.EventID)
+	}
+	return nil
+}
+
+// deleteBooking удаляет бронирование вместе с его напоминаниями (как ON DELETE CASCADE)
+// и пишет BookingDeleted. Вызывать под s.mu.
+func (s *Storage) deleteBooking(booking models.Booking) {
+	delete(s.bookings, booking.ID)
+	for id, entry := range s.reminders {
+		if entry.reminder.BookingID == booking.ID {
+			delete(s.reminders, id)
+		}
+	}
+	s.bookingOutbox(bus.BookingDeleted, booking)
+}
+
+func (s *Storage) GetRolePermissions(ctx context.Context) (map[string][]string, error) {
+	perms := make(map[string][]string, len(rolePermissions))
+	for role, list := range rolePermissions {
+		perms[role] = append([]string(nil), list...)
+	}
+	return perms, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// checkVersion сравнивает текущую версию записи с ожидаемой клиентом; expected == 0 — без проверки.
+func checkVersion(current, expected int64) error {
+	if expected != 0 && current != expected {
+		return fmt.Errorf("version %d, expected %d: %w", current, expected, storage.ErrVersionMismatch)
+	}
+	return nil
+}
+
+// emailTaken проверяет уникальность email среди всех пользователей, кроме exceptID.
+// Вызывать под s.mu.
+func (s *Storage) emailTaken(email string, exceptID int64) bool {
+	for _, user := range s.users {
+		if user.ID != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBookingRefs — аналог REFERENCES events(id), users(id). Вызывать под s.mu.
+func (s *Storage) checkBookingRefs(eventID, userID int64) error {
+	if _, ok := s.events[eventID]; !ok {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrForeignKey)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("user %d: %w", userID, storage.ErrForeignKey)
+	}
+	return nil
+}
+
+// bookedSeats — число занятых мест на событии: неотменённые бронирования и действующие холды.
+// Вызывать под s.mu.
+func (s *Storage) bookedSeats(eventID int64) int {
+	booked := 0
+	for _, booking := range s.bookings {
+		if booking.EventID == eventID && booking.Status != models.BookingCancelled {
+			booked++
+		}
+	}
+	now := time.Now()
+	for _, hold := range s.holds {
+		if hold.EventID == eventID && hold.ExpiresAt.After(now) {
+			booked++
+		}
+	}
+	return booked
+}
+
+// checkSeat возвращает storage.ErrEventStarted, если событие уже началось,
+// и storage.ErrEventFull, если на нём не осталось мест. Вызывать под s.mu.
+func (s *Storage) checkSeat(eventID int64) error {
+	if !s.events[eventID].StartAt.After(time.Now()) {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrEventStarted)
+	}
+	if s.bookedSeats(eventID) >= s.events[eventID].Capacity {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrEventFull)
+	}
+	return nil
+}
+
+func (s *Storage) withRemainingSeats(event models.Event) models.Event {
+	event.RemainingSeats = event.Capacity - s.bookedSeats(event.ID)
+	return event
+}
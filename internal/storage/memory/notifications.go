@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) ReserveNotification(ctx context.Context, msgID int64) (bool, error) {
+	const op = "storage.memory.ReserveNotification"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findOutbox(msgID)
+	if !ok {
+		// как внешний ключ на outbox
+		return false, fmt.Errorf("%s: %w", op, storage.ErrForeignKey)
+	}
+	if s.outbox[i].notified {
+		return false, nil
+	}
+	s.outbox[i].notified = true
+	return true, nil
+}
+
+func (s *Storage) ReleaseNotification(ctx context.Context, msgID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, ok := s.findOutbox(msgID); ok {
+		s.outbox[i].notified = false
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// outboxEntry — сообщение outbox с состоянием доставки, как строка таблицы outbox.
+type outboxEntry struct {
+	msg         models.OutboxMessage
+	nextAttempt time.Time
+	lockedUntil time.Time // аренда диспетчером; нулевое — свободно
+	lastError   string
+	failedAt    time.Time // исчерпаны попытки; нулевое — ещё доставляется
+	notified    bool      // письма по сообщению уже в очереди, как строка outbox_notifications
+}
+
+// addOutbox записывает доменное событие вместе с изменением. Вызывать под s.mu.Lock —
+// так сообщение и изменение видны одновременно, как при общей транзакции в postgre.
+func (s *Storage) addOutbox(msgType, aggregateType string, aggregateID int64, payload any) {
+	// снимки моделей сериализуются всегда, ошибка возможна только при ошибке в коде
+	data, err := json.Marshal(payload)
+	if err != nil {
+		panic(fmt.Sprintf("marshal %s payload: %v", msgType, err))
+	}
+	s.lastOutboxID++
+	now := time.Now()
+	s.outbox = append(s.outbox, outboxEntry{
+		msg: models.OutboxMessage{
+			ID:            s.lastOutboxID,
+			Type:          msgType,
+			AggregateType: aggregateType,
+			AggregateID:   aggregateID,
+			Payload:       data,
+			CreatedAt:     now,
+		},
+		nextAttempt: now,
+	})
+}
+
+func (s *Storage) userOutbox(msgType string, user models.User) {
+	s.addOutbox(msgType, bus.AggregateUser, user.ID, user)
+}
+
+func (s *Storage) eventOutbox(msgType string, event models.Event) {
+	s.addOutbox(msgType, bus.AggregateEvent, event.ID, s.withRemainingSeats(event))
+}
+
+func (s *Storage) bookingOutbox(msgType string, booking models.Booking) {
+	s.addOutbox(msgType, bus.AggregateBooking, booking.ID, booking)
+}
+
+// eventUpdatedOutbox — как в postgre: EventUpdated, а при смене расписания относительно before ещё EventRescheduled.
+func (s *Storage) eventUpdatedOutbox(before, event models.Event) {
+	s.eventOutbox(bus.EventUpdated, event)
+	if !event.SameSchedule(before) {
+		s.eventOutbox(bus.EventRescheduled, event)
+	}
+}
+
+// bookingStatusMessages — тип сообщения для каждого статуса, в который бронирование переводит SetBookingStatus.
+var bookingStatusMessages = map[string]string{
+	models.BookingConfirmed: bus.BookingConfirmed,
+	models.BookingCancelled: bus.BookingCancelled,
+	models.BookingAttended:  bus.BookingAttended,
+}
+
+func (s *Storage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type aggregate struct {
+		kind string
+		id   int64
+	}
+	now := time.Now()
+	// s.outbox упорядочен по ID, поэтому первое встреченное сообщение агрегата — самое раннее
+	seen := make(map[aggregate]bool)
+	var messages []models.OutboxMessage
+	for i := range s.outbox {
+		if len(messages) >= limit {
+			break
+		}
+		entry := &s.outbox[i]
+		if !entry.failedAt.IsZero() {
+			continue // недоставляемое не задерживает агрегат
+		}
+		key := aggregate{entry.msg.AggregateType, entry.msg.AggregateID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if entry.nextAttempt.After(now) || entry.lockedUntil.After(now) {
+			continue
+		}
+		entry.lockedUntil = now.Add(lease)
+		messages = append(messages, entry.msg)
+	}
+	return messages, nil
+}
+
+func (s *Storage) AckOutbox(ctx context.Context, id int64) error {
+	const op = "storage.memory.AckOutbox"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findOutbox(id)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+	return nil
+}
+
+func (s *Storage) RetryOutbox(ctx context.Context, id int64, next time.Time, reason string) error {
+	const op = "storage.memory.RetryOutbox"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findOutbox(id)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	entry := &s.outbox[i]
+	entry.msg.Attempts++
+	entry.lastError = reason
+	entry.nextAttempt = next
+	entry.lockedUntil = time.Time{}
+	return nil
+}
+
+func (s *Storage) FailOutbox(ctx context.Context, id int64, reason string) error {
+	const op = "storage.memory.FailOutbox"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findOutbox(id)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	entry := &s.outbox[i]
+	entry.msg.Attempts++
+	entry.lastError = reason
+	entry.failedAt = time.Now()
+	entry.lockedUntil = time.Time{}
+	return nil
+}
+
+func (s *Storage) findOutbox(id int64) (int, bool) {
+	for i, entry := range s.outbox {
+		if entry.msg.ID == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) PatchUser(ctx context.Context, id, version int64, patch storage.UserPatch) error {
+	const op = "storage.memory.PatchUser"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(user.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if patch.Email != nil {
+		if s.emailTaken(*patch.Email, id) {
+			return fmt.Errorf("%s: email %q: %w", op, *patch.Email, storage.ErrConflict)
+		}
+		user.Email = *patch.Email
+	}
+	if patch.Role != nil {
+		if _, ok := rolePermissions[*patch.Role]; !ok {
+			return fmt.Errorf("%s: role %q: %w", op, *patch.Role, storage.ErrForeignKey)
+		}
+		user.Role = *patch.Role
+	}
+	if patch.Name != nil {
+		user.Name = *patch.Name
+	}
+	user.Version++
+	s.users[id] = user
+	s.userOutbox(bus.UserUpdated, user)
+	return nil
+}
+
+func (s *Storage) PatchEvent(ctx context.Context, id, version int64, patch storage.EventPatch) error {
+	const op = "storage.memory.PatchEvent"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }() // выполнится после Unlock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(event.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	before := event
+	if patch.Capacity != nil {
+		if booked := s.bookedSeats(id); *patch.Capacity < booked {
+			return fmt.Errorf("%s: capacity %d is less than %d booked seats: %w", op, *patch.Capacity, booked, storage.ErrConflict)
+		}
+		event.Capacity = *patch.Capacity
+	}
+	if patch.Title != nil {
+		event.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		event.Description = *patch.Description
+	}
+	if patch.StartAt != nil {
+		event.StartAt = *patch.StartAt
+	}
+	if patch.EndAt != nil {
+		event.EndAt = *patch.EndAt
+	}
+	if patch.TimeZone != nil {
+		event.TimeZone = *patch.TimeZone
+	}
+	event.Version++
+	s.events[id] = event
+	if patch.Capacity != nil {
+		promoted = s.promoteWaitlist(id)
+	}
+	s.eventUpdatedOutbox(before, event)
+	return nil
+}
+
+func (s *Storage) PatchBooking(ctx context.Context, id, version int64, patch storage.BookingPatch) error {
+	const op = "storage.memory.PatchBooking"
+	var promoted []models.Booking
+	defer func() { s.publishPromoted(promoted) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	booking, ok := s.bookings[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err := checkVersion(booking.Version, version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !booking.Active() {
+		return fmt.Errorf("%s: booking %d is %s: %w", op, id, booking.Status, storage.ErrInvalidStatus)
+	}
+	eventID, userID := booking.EventID, booking.UserID
+	if patch.EventID != nil {
+		eventID = *patch.EventID
+	}
+	if patch.UserID != nil {
+		userID = *patch.UserID
+	}
+	if err := s.checkBookingRefs(eventID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	previousEventID := booking.EventID
+	if eventID != previousEventID {
+		if err := s.checkSeat(eventID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	booking.EventID, booking.UserID = eventID, userID
+	booking.Version++
+	s.bookings[id] = booking
+	s.bookingOutbox(bus.BookingUpdated, booking)
+	if eventID != previousEventID {
+		promoted = s.promoteWaitlist(previousEventID)
+	}
+	return nil
+}
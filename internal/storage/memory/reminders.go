@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// reminderEntry — напоминание с арендой планировщиком, как строка reminders.
+type reminderEntry struct {
+	reminder    models.Reminder
+	lockedUntil time.Time // нулевое — свободно
+}
+
+func (s *Storage) ScheduleReminders(ctx context.Context, bookingID int64, offsets []time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scheduleReminders(func(b models.Booking) bool { return b.ID == bookingID }, offsets), nil
+}
+
+func (s *Storage) RescheduleEventReminders(ctx context.Context, eventID int64, offsets []time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scheduleReminders(func(b models.Booking) bool { return b.EventID == eventID }, offsets), nil
+}
+
+// scheduleReminders пересчитывает и создаёт напоминания бронирований, подходящих под match. Вызывать под s.mu.Lock.
+func (s *Storage) scheduleReminders(match func(models.Booking) bool, offsets []time.Duration) int {
+	now := time.Now()
+	created := 0
+	for _, booking := range s.bookings {
+		if !match(booking) {
+			continue
+		}
+		start := s.events[booking.EventID].StartAt
+		existing := make(map[time.Duration]bool)
+		for id, entry := range s.reminders {
+			if entry.reminder.BookingID != booking.ID {
+				continue
+			}
+			existing[entry.reminder.Offset] = true
+			if entry.reminder.Status == models.ReminderPending {
+				entry.reminder.DueAt = start.Add(-entry.reminder.Offset)
+				s.reminders[id] = entry
+			}
+		}
+		if !booking.Active() {
+			continue
+		}
+		for _, offset := range offsets {
+			offset = offset.Truncate(time.Second) // как offset_seconds
+			if existing[offset] || !start.Add(-offset).After(now) {
+				continue
+			}
+			existing[offset] = true
+			s.lastReminderID++
+			s.reminders[s.lastReminderID] = reminderEntry{reminder: models.Reminder{
+				ID:        s.lastReminderID,
+				BookingID: booking.ID,
+				Offset:    offset,
+				DueAt:     start.Add(-offset),
+				Status:    models.ReminderPending,
+			}}
+			created++
+		}
+	}
+	return created
+}
+
+func (s *Storage) CancelReminders(ctx context.Context, bookingID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancelled := 0
+	for id, entry := range s.reminders {
+		if entry.reminder.BookingID == bookingID && entry.reminder.Status == models.ReminderPending {
+			entry.reminder.Status = models.ReminderCancelled
+			entry.lockedUntil = time.Time{}
+			s.reminders[id] = entry
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+func (s *Storage) ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]models.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	// ближайшее к началу наступившее напоминание каждого бронирования
+	nearest := make(map[int64]time.Duration)
+	for id, entry := range s.reminders {
+		r := entry.reminder
+		if _, ok := s.bookings[r.BookingID]; !ok {
+			delete(s.reminders, id) // как ON DELETE CASCADE
+			continue
+		}
+		if r.Status != models.ReminderPending || r.DueAt.After(now) {
+			continue
+		}
+		if offset, ok := nearest[r.BookingID]; !ok || r.Offset < offset {
+			nearest[r.BookingID] = r.Offset
+		}
+	}
+
+	var due []models.Reminder
+	for _, entry := range s.reminders {
+		r := entry.reminder
+		if r.Status == models.ReminderPending && !r.DueAt.After(now) && !entry.lockedUntil.After(now) {
+			due = append(due, r)
+		}
+	}
+	// как ORDER BY due_at, id
+	slices.SortFunc(due, func(a, b models.Reminder) int {
+		if c := a.DueAt.Compare(b.DueAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var live []models.Reminder
+	for _, r := range due {
+		entry := s.reminders[r.ID]
+		booking := s.bookings[r.BookingID]
+		if booking.Active() && s.events[booking.EventID].StartAt.After(now) && nearest[r.BookingID] == r.Offset {
+			entry.lockedUntil = now.Add(lease)
+			live = append(live, r)
+		} else {
+			entry.reminder.Status = models.ReminderCancelled
+		}
+		s.reminders[r.ID] = entry
+	}
+	return live, nil
+}
+
+func (s *Storage) CompleteReminder(ctx context.Context, id int64) error {
+	const op = "storage.memory.CompleteReminder"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.reminders[id]
+	if !ok || entry.reminder.Status != models.ReminderPending {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	now := time.Now()
+	entry.reminder.Status = models.ReminderSent
+	entry.reminder.SentAt = &now
+	entry.lockedUntil = time.Time{}
+	s.reminders[id] = entry
+	return nil
+}
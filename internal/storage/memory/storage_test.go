@@ -0,0 +1,17 @@
+package memory
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New(slog.New(slog.NewTextHandler(io.Discard, nil)), bus.New())
+	})
+}
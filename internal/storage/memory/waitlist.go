@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) JoinWaitlist(ctx context.Context, eventID, userID int64) (models.WaitlistEntry, error) {
+	const op = "storage.memory.JoinWaitlist"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: user %d: %w", op, userID, storage.ErrForeignKey)
+	}
+	if !event.StartAt.After(time.Now()) {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: event %d: %w", op, eventID, storage.ErrEventStarted)
+	}
+	if s.bookedSeats(eventID) < event.Capacity {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: event %d: %w", op, eventID, storage.ErrEventNotFull)
+	}
+	if s.hasActiveBooking(eventID, userID) {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: user %d already booked event %d: %w", op, userID, eventID, storage.ErrConflict)
+	}
+	if _, ok := s.findWaitlistEntry(eventID, userID); ok {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: user %d already waits for event %d: %w", op, userID, eventID, storage.ErrConflict)
+	}
+
+	s.lastWaitlistID++
+	entry := models.WaitlistEntry{ID: s.lastWaitlistID, EventID: eventID, UserID: userID, CreatedAt: time.Now()}
+	s.waitlist[entry.ID] = entry
+	return s.withPosition(entry), nil
+}
+
+func (s *Storage) GetWaitlistEntry(ctx context.Context, eventID, userID int64) (models.WaitlistEntry, error) {
+	const op = "storage.memory.GetWaitlistEntry"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.findWaitlistEntry(eventID, userID)
+	if !ok {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return s.withPosition(entry), nil
+}
+
+func (s *Storage) LeaveWaitlist(ctx context.Context, eventID, userID int64) error {
+	const op = "storage.memory.LeaveWaitlist"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.findWaitlistEntry(eventID, userID)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	delete(s.waitlist, entry.ID)
+	return nil
+}
+
+// findWaitlistEntry ищет пользователя в очереди события. Вызывать под s.mu.
+func (s *Storage) findWaitlistEntry(eventID, userID int64) (models.WaitlistEntry, bool) {
+	for _, entry := range s.waitlist {
+		if entry.EventID == eventID && entry.UserID == userID {
+			return entry, true
+		}
+	}
+	return models.WaitlistEntry{}, false
+}
+
+// withPosition вычисляет номер в очереди. Вызывать под s.mu.
+func (s *Storage) withPosition(entry models.WaitlistEntry) models.WaitlistEntry {
+	entry.Position = 0
+	for _, other := range s.waitlist {
+		if other.EventID == entry.EventID && other.ID <= entry.ID {
+			entry.Position++
+		}
+	}
+	return entry
+}
+
+// hasActiveBooking сообщает, есть ли у пользователя неотменённое бронирование на событие. Вызывать под s.mu.
+func (s *Storage) hasActiveBooking(eventID, userID int64) bool {
+	for _, booking := range s.bookings {
+		if booking.EventID == eventID && booking.UserID == userID && booking.Status != models.BookingCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// promoteWaitlist отдаёт свободные места события первым в очереди, создавая им бронирования
+// в статусе pending. Для начавшихся событий ничего не делает. Вызывать под s.mu.Lock.
+func (s *Storage) promoteWaitlist(eventID int64) []models.Booking {
+	event, ok := s.events[eventID]
+	if !ok || !event.StartAt.After(time.Now()) {
+		return nil
+	}
+
+	var promoted []models.Booking
+	for free := event.Capacity - s.bookedSeats(eventID); free > 0; {
+		var first models.WaitlistEntry
+		for _, entry := range s.waitlist {
+			if entry.EventID == eventID && (first.ID == 0 || entry.ID < first.ID) {
+				first = entry
+			}
+		}
+		if first.ID == 0 {
+			break // очередь пуста
+		}
+		delete(s.waitlist, first.ID)
+		// пользователь мог забронировать место напрямую, пока стоял в очереди
+		if s.hasActiveBooking(eventID, first.UserID) {
+			continue
+		}
+		s.lastBookingID++
+		booking := models.Booking{
+			ID:        s.lastBookingID,
+			EventID:   eventID,
+			UserID:    first.UserID,
+			Status:    models.BookingPending,
+			CreatedAt: time.Now(),
+			Version:   1,
+		}
+		s.bookings[booking.ID] = booking
+		s.bookingOutbox(bus.BookingCreated, booking)
+		promoted = append(promoted, booking)
+		free--
+	}
+	return promoted
+}
+
+// publishPromoted сообщает о бронированиях, выданных из очереди.
+// Вызывать после снятия s.mu, чтобы подписчики могли обращаться к хранилищу.
+func (s *Storage) publishPromoted(bookings []models.Booking) {
+	for _, booking := range bookings {
+		s.log.Info("waitlist promoted",
+			slog.Int64("booking_id", booking.ID), slog.Int64("event_id", booking.EventID), slog.Int64("user_id", booking.UserID))
+		if s.bus != nil {
+			s.bus.Publish(bus.Message{
+				Type:          bus.WaitlistPromoted,
+				AggregateType: "booking",
+				AggregateID:   booking.ID,
+				Payload:       booking,
+			})
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// deliveryEntry — доставка с арендой воркером, как строка webhook_deliveries.
+type deliveryEntry struct {
+	delivery    models.WebhookDelivery
+	lockedUntil time.Time // нулевое — свободна
+}
+
+func (s *Storage) AddWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWebhookID++
+	webhook = models.Webhook{
+		ID:         s.lastWebhookID,
+		URL:        webhook.URL,
+		EventTypes: slices.Clone(webhook.EventTypes),
+		Secret:     webhook.Secret,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}
+	s.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (s *Storage) GetAllWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := make([]models.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	slices.SortFunc(webhooks, func(a, b models.Webhook) int { return cmp.Compare(a.ID, b.ID) })
+	return webhooks, nil
+}
+
+func (s *Storage) GetWebhookByID(ctx context.Context, id int64) (models.Webhook, error) {
+	const op = "storage.memory.GetWebhookByID"
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return webhook, nil
+}
+
+func (s *Storage) EnableWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	const op = "storage.memory.EnableWebhook"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	webhook.Active, webhook.ConsecutiveFailures, webhook.DisabledAt = true, 0, nil
+	s.webhooks[id] = webhook
+	now := time.Now()
+	for deliveryID, entry := range s.deliveries {
+		if entry.delivery.WebhookID == id && entry.delivery.Status == models.DeliveryPending {
+			entry.delivery.NextAttemptAt = &now
+			s.deliveries[deliveryID] = entry
+		}
+	}
+	return webhook, nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.memory.DeleteWebhook"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	delete(s.webhooks, id)
+	// ON DELETE CASCADE
+	for deliveryID, entry := range s.deliveries {
+		if entry.delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) EnqueueWebhookDeliveries(ctx context.Context, msg models.OutboxMessage, payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enqueued := 0
+	now := time.Now()
+	for _, webhook := range s.webhooks {
+		if !webhook.Subscribed(msg.Type) || s.hasDelivery(webhook.ID, msg.ID) {
+			continue
+		}
+		s.lastDeliveryID++
+		s.deliveries[s.lastDeliveryID] = deliveryEntry{delivery: models.WebhookDelivery{
+			ID:            s.lastDeliveryID,
+			WebhookID:     webhook.ID,
+			MessageID:     msg.ID,
+			EventType:     msg.Type,
+			Payload:       slices.Clone(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		}}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// hasDelivery — аналог UNIQUE (webhook_id, message_id). Вызывать под s.mu.
+func (s *Storage) hasDelivery(webhookID, messageID int64) bool {
+	for _, entry := range s.deliveries {
+		if entry.delivery.WebhookID == webhookID && entry.delivery.MessageID == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) GetWebhookDeliveries(ctx context.Context, filter storage.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, entry := range s.deliveries {
+		if entry.delivery.WebhookID != filter.WebhookID {
+			continue
+		}
+		if filter.Status != "" && entry.delivery.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, entry.delivery)
+	}
+	return paginate(deliveries, filter.Page, storage.DeliveryKey), nil
+}
+
+func (s *Storage) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due []models.WebhookDelivery
+	for _, entry := range s.deliveries {
+		d := entry.delivery
+		if d.Status != models.DeliveryPending || !s.webhooks[d.WebhookID].Active ||
+			d.NextAttemptAt.After(now) || entry.lockedUntil.After(now) {
+			continue
+		}
+		due = append(due, d)
+	}
+	// как ORDER BY next_attempt_at, id
+	slices.SortFunc(due, func(a, b models.WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, d := range due {
+		entry := s.deliveries[d.ID]
+		entry.lockedUntil = now.Add(lease)
+		s.deliveries[d.ID] = entry
+	}
+	return due, nil
+}
+
+func (s *Storage) CompleteWebhookDelivery(ctx context.Context, id int64, attempt storage.WebhookAttempt) (bool, error) {
+	const op = "storage.memory.CompleteWebhookDelivery"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.deliveries[id]
+	if !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	d := &entry.delivery
+	d.Attempts++
+	d.ResponseStatus = attempt.ResponseStatus
+	entry.lockedUntil = time.Time{}
+	webhook := s.webhooks[d.WebhookID]
+	disabled := false
+
+	if attempt.Error == "" {
+		now := time.Now()
+		d.Status, d.LastError, d.NextAttemptAt, d.DeliveredAt = models.DeliverySucceeded, "", nil, &now
+		webhook.ConsecutiveFailures = 0
+	} else {
+		d.LastError = attempt.Error
+		if attempt.NextAttemptAt.IsZero() {
+			d.Status, d.NextAttemptAt = models.DeliveryFailed, nil
+		} else {
+			next := attempt.NextAttemptAt
+			d.NextAttemptAt = &next
+		}
+		webhook.ConsecutiveFailures++
+		if webhook.Active && attempt.MaxFailures > 0 && webhook.ConsecutiveFailures >= attempt.MaxFailures {
+			now := time.Now()
+			webhook.Active, webhook.DisabledAt = false, &now
+			disabled = true
+		}
+	}
+	s.deliveries[id] = entry
+	s.webhooks[webhook.ID] = webhook
+	return disabled, nil
+}
@@ -0,0 +1,28 @@
+package storage
+
+import "time"
+
+// Патчи — частичные обновления (PATCH): nil-поле не меняется, в UPDATE попадают только заданные.
+
+// UserPatch — изменяемые поля пользователя.
+type UserPatch struct {
+	Name  *string
+	Email *string
+	Role  *string
+}
+
+// EventPatch — изменяемые поля события; организатор через патч не меняется.
+type EventPatch struct {
+	Title       *string
+	Description *string
+	StartAt     *time.Time
+	EndAt       *time.Time
+	TimeZone    *string
+	Capacity    *int
+}
+
+// BookingPatch — изменяемые поля бронирования; статус меняется только через SetBookingStatus.
+type BookingPatch struct {
+	EventID *int64
+	UserID  *int64
+}
@@ -0,0 +1,62 @@
+package postgre
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"TRYREST/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// коды ошибок postgres, см. https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeUniqueViolation      = "23505"
+	codeForeignKeyViolation  = "23503"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeTooManyConnections   = "53300"
+	codeAdminShutdown        = "57P01"
+	codeCrashShutdown        = "57P02"
+	codeCannotConnectNow     = "57P03"
+)
+
+// classify добавляет к ошибке драйвера подходящую ошибку из пакета storage,
+// сохраняя исходную в цепочке. Неизвестные ошибки возвращаются как есть.
+func classify(err error) error {
+	if kind := kindOf(err); kind != nil {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+	return err
+}
+
+func kindOf(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == codeUniqueViolation:
+			return storage.ErrConflict
+		case pqErr.Code == codeForeignKeyViolation:
+			return storage.ErrForeignKey
+		case pqErr.Code == codeSerializationFailure, pqErr.Code == codeDeadlockDetected:
+			return storage.ErrRetryable
+		case strings.HasPrefix(string(pqErr.Code), "08"), // connection exception
+			pqErr.Code == codeTooManyConnections,
+			pqErr.Code == codeAdminShutdown,
+			pqErr.Code == codeCrashShutdown,
+			pqErr.Code == codeCannotConnectNow:
+			return storage.ErrUnavailable
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return storage.ErrUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) AddHold(ctx context.Context, eventID, userID int64, ttl time.Duration) (_ models.Hold, err error) {
+	const op = "storage.postgre.AddHold"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var hold models.Hold
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		st, err := lockEvent(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		if st.started {
+			return fmt.Errorf("event %d: %w", eventID, storage.ErrEventStarted)
+		}
+		if st.booked >= st.capacity {
+			return fmt.Errorf("event %d: %w", eventID, storage.ErrEventFull)
+		}
+		var held bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM holds WHERE event_id = $1 AND user_id = $2 AND expires_at > now())",
+			eventID, userID,
+		).Scan(&held)
+		if err != nil {
+			return classify(err)
+		}
+		if held {
+			return fmt.Errorf("user %d already holds a seat on event %d: %w", userID, eventID, storage.ErrConflict)
+		}
+
+		hold, err = scanHold(tx.QueryRowContext(ctx,
+			"INSERT INTO holds AS h (event_id, user_id, expires_at) VALUES ($1, $2, now() + $3 * INTERVAL '1 millisecond') RETURNING "+holdColumns,
+			eventID, userID, ttl.Milliseconds(),
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert hold", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Hold{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return hold, nil
+}
+
+func (s *Storage) GetHoldByID(ctx context.Context, id int64) (_ models.Hold, err error) {
+	const op = "storage.postgre.GetHoldByID"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	hold, err := scanHold(s.db.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds h WHERE h.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Hold{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query hold by ID", slog.String("op", op), slog.Any("error", err))
+		return models.Hold{}, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return hold, nil
+}
+
+func (s *Storage) ConfirmHold(ctx context.Context, id int64) (_ models.Booking, err error) {
+	const op = "storage.postgre.ConfirmHold"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var booking models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		var hold models.Hold
+		var expired bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT "+holdColumns+", h.expires_at <= now() FROM holds h WHERE h.id = $1 FOR UPDATE", id,
+		).Scan(&hold.ID, &hold.EventID, &hold.UserID, &hold.CreatedAt, &hold.ExpiresAt, &expired)
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return classify(err)
+		}
+		if expired {
+			return fmt.Errorf("hold %d: %w", id, storage.ErrHoldExpired)
+		}
+		// место уже учтено холдом, поэтому вместимость не проверяем — только что событие не началось
+		st, err := lockEvent(ctx, tx, hold.EventID)
+		if err != nil {
+			return err
+		}
+		if st.started {
+			return fmt.Errorf("event %d: %w", hold.EventID, storage.ErrEventStarted)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM holds WHERE id = $1", id); err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete hold", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		booking, err = scanBooking(tx.QueryRowContext(ctx,
+			"INSERT INTO bookings AS b (event_id, user_id, status, confirmed_at) VALUES ($1, $2, $3, now()) RETURNING "+bookingColumns,
+			hold.EventID, hold.UserID, models.BookingConfirmed,
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert booking", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return bookingOutbox(ctx, tx, bus.BookingCreated, booking)
+	})
+	if err != nil {
+		return models.Booking{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return booking, nil
+}
+
+func (s *Storage) ReleaseHold(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgre.ReleaseHold"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		var eventID int64
+		err := tx.QueryRowContext(ctx, "DELETE FROM holds WHERE id = $1 RETURNING event_id", id).Scan(&eventID)
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete hold", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		promoted, err = promoteWaitlist(ctx, tx, eventID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.publishPromoted(ctx, promoted)
+	return nil
+}
+
+func (s *Storage) ReleaseExpiredHolds(ctx context.Context) (_ int, err error) {
+	const op = "storage.postgre.ReleaseExpiredHolds"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var released int
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "DELETE FROM holds WHERE expires_at <= now() RETURNING event_id")
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete expired holds", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		events := make(map[int64]bool)
+		for rows.Next() {
+			var eventID int64
+			if err := rows.Scan(&eventID); err != nil {
+				rows.Close()
+				return classify(err)
+			}
+			events[eventID] = true
+			released++
+		}
+		if err := rows.Close(); err != nil {
+			return classify(err)
+		}
+		if err := rows.Err(); err != nil {
+			return classify(err)
+		}
+
+		// события блокируются по возрастанию id, как и в других транзакциях с несколькими событиями:
+		// иначе два реапера или реапер и перенос бронирования могут заблокировать друг друга
+		for _, eventID := range slices.Sorted(maps.Keys(events)) {
+			p, err := promoteWaitlist(ctx, tx, eventID)
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, p...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s.publishPromoted(ctx, promoted)
+	return released, nil
+}
+
+// holdColumns — колонки холда для SELECT ... FROM holds h; порядок совпадает со scanHold.
+const holdColumns = `h.id, h.event_id, h.user_id, h.created_at, h.expires_at`
+
+func scanHold(row rowScanner) (models.Hold, error) {
+	var hold models.Hold
+	err := row.Scan(&hold.ID, &hold.EventID, &hold.UserID, &hold.CreatedAt, &hold.ExpiresAt)
+	return hold, err
+}
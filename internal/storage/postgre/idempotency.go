@@ -0,0 +1,133 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) (_ models.IdempotencyRecord, _ bool, err error) {
+	const op = "storage.postgre.ReserveIdempotencyKey"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var existing models.IdempotencyRecord
+	reserved := false
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// истёкший ключ занимаем заново; остальные истёкшие удаляет PurgeExpiredIdempotencyKeys
+		if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at <= now()",
+			rec.UserID, rec.Key); err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete expired idempotency key", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, key) DO NOTHING`,
+			rec.UserID, rec.Key, rec.Fingerprint, rec.ExpiresAt,
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert idempotency key", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return classify(err)
+		} else if n == 1 {
+			reserved = true
+			return nil
+		}
+
+		existing, err = scanIdempotencyRecord(tx.QueryRowContext(ctx,
+			"SELECT "+idempotencyColumns+" FROM idempotency_keys i WHERE i.user_id = $1 AND i.key = $2", rec.UserID, rec.Key,
+		))
+		if err == sql.ErrNoRows {
+			// ключ освободили между INSERT и SELECT — для клиента это тот же параллельный запрос
+			return fmt.Errorf("key %q: %w", rec.Key, storage.ErrConflict)
+		}
+		return classify(err)
+	})
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if reserved {
+		return rec, true, nil
+	}
+	return existing, false, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, userID int64, key string, statusCode int, header http.Header, body []byte) (err error) {
+	const op = "storage.postgre.CompleteIdempotencyKey"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("%s: encode headers: %w", op, err)
+	}
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, headers = $2, body = $3 WHERE user_id = $4 AND key = $5",
+		statusCode, headers, body, userID, key,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to save idempotent response", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, userID int64, key string) (err error) {
+	const op = "storage.postgre.ReleaseIdempotencyKey"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key); err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete idempotency key", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return nil
+}
+
+func (s *Storage) PurgeExpiredIdempotencyKeys(ctx context.Context) (_ int, err error) {
+	const op = "storage.postgre.PurgeExpiredIdempotencyKeys"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete expired idempotency keys", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return int(purged), nil
+}
+
+// idempotencyColumns — колонки для SELECT ... FROM idempotency_keys i; порядок совпадает со scanIdempotencyRecord.
+const idempotencyColumns = `i.user_id, i.key, i.fingerprint, COALESCE(i.status_code, 0), i.headers, i.body,
+	i.created_at, i.expires_at`
+
+func scanIdempotencyRecord(row rowScanner) (models.IdempotencyRecord, error) {
+	var rec models.IdempotencyRecord
+	var headers []byte
+	err := row.Scan(&rec.UserID, &rec.Key, &rec.Fingerprint, &rec.StatusCode, &headers, &rec.Body,
+		&rec.CreatedAt, &rec.ExpiresAt)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(headers, &rec.Header); err != nil {
+		return rec, fmt.Errorf("decode headers: %w", err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,64 @@
+package postgre
+
+import (
+	"fmt"
+	"strings"
+
+	"TRYREST/internal/storage"
+)
+
+// sortColumn — SQL-выражение поля сортировки и тип, к которому приводится значение из курсора.
+type sortColumn struct {
+	expr string
+	cast string
+}
+
+var (
+	userSortColumns = map[string]sortColumn{
+		"name":  {expr: "u.name", cast: "text"},
+		"email": {expr: "u.email", cast: "text"},
+	}
+	eventSortColumns = map[string]sortColumn{
+		"start_at": {expr: "e.start_at", cast: "timestamptz"},
+		"title":    {expr: "e.title", cast: "text"},
+	}
+)
+
+// keyset дописывает к запросу (который уже содержит WHERE) условие курсора, ORDER BY и LIMIT.
+// Сортировка всегда идёт по паре (поле, id), поэтому порядок стабилен и при равных значениях поля.
+func keyset(query string, args []any, page storage.Page, columns map[string]sortColumn, idExpr string) (string, []any) {
+	col, bySort := columns[page.Sort]
+
+	cmp, dir := ">", "ASC"
+	if page.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if page.After != nil {
+		if bySort {
+			args = append(args, page.After.Value, page.After.ID)
+			query += fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d)", col.expr, idExpr, cmp, len(args)-1, col.cast, len(args))
+		} else {
+			args = append(args, page.After.ID)
+			query += fmt.Sprintf(" AND %s %s $%d", idExpr, cmp, len(args))
+		}
+	}
+
+	if bySort {
+		query += fmt.Sprintf(" ORDER BY %s %s, %s %s", col.expr, dir, idExpr, dir)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s", idExpr, dir)
+	}
+
+	if page.Limit > 0 {
+		args = append(args, page.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
+
+// likePattern превращает подстроку в шаблон для ILIKE, экранируя спецсимволы.
+func likePattern(substr string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(substr) + "%"
+}
@@ -0,0 +1,35 @@
+package postgre
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+func (s *Storage) ReserveNotification(ctx context.Context, msgID int64) (_ bool, err error) {
+	const op = "storage.postgre.ReserveNotification"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx, "INSERT INTO outbox_notifications (outbox_id) VALUES ($1) ON CONFLICT DO NOTHING", msgID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to insert notification mark", slog.String("op", op), slog.Any("error", err))
+		return false, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return false, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *Storage) ReleaseNotification(ctx context.Context, msgID int64) (err error) {
+	const op = "storage.postgre.ReleaseNotification"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM outbox_notifications WHERE outbox_id = $1", msgID); err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete notification mark", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return nil
+}
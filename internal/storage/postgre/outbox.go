@@ -0,0 +1,201 @@
+package postgre
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// addOutbox записывает доменное событие в outbox в транзакции изменения: сообщение появится
+// только вместе с закоммиченным изменением. payload — снимок сущности, сериализуется в JSON.
+func addOutbox(ctx context.Context, tx *sql.Tx, msgType, aggregateType string, aggregateID int64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", msgType, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox (type, aggregate_type, aggregate_id, payload) VALUES ($1, $2, $3, $4)",
+		msgType, aggregateType, aggregateID, data,
+	)
+	return classify(err)
+}
+
+func userOutbox(ctx context.Context, tx *sql.Tx, msgType string, user models.User) error {
+	return addOutbox(ctx, tx, msgType, bus.AggregateUser, user.ID, user)
+}
+
+func bookingOutbox(ctx context.Context, tx *sql.Tx, msgType string, booking models.Booking) error {
+	return addOutbox(ctx, tx, msgType, bus.AggregateBooking, booking.ID, booking)
+}
+
+// eventOutbox перечитывает событие в транзакции, чтобы снимок включал свободные места после изменения.
+func eventOutbox(ctx context.Context, tx *sql.Tx, msgType string, id int64) error {
+	event, err := scanEvent(tx.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events e WHERE e.id = $1", id))
+	if err != nil {
+		return classify(err)
+	}
+	return addOutbox(ctx, tx, msgType, bus.AggregateEvent, event.ID, event)
+}
+
+// eventSchedule читает начало, окончание и часовой пояс события до изменения — для eventUpdatedOutbox.
+func eventSchedule(ctx context.Context, tx *sql.Tx, id int64) (models.Event, error) {
+	event := models.Event{ID: id}
+	err := tx.QueryRowContext(ctx, "SELECT start_at, end_at, time_zone FROM events WHERE id = $1", id).
+		Scan(&event.StartAt, &event.EndAt, &event.TimeZone)
+	if err == sql.ErrNoRows {
+		return models.Event{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Event{}, classify(err)
+	}
+	return event, nil
+}
+
+// eventUpdatedOutbox пишет EventUpdated, а если начало, окончание или часовой пояс отличаются
+// от before (события до изменения) — ещё и EventRescheduled.
+func eventUpdatedOutbox(ctx context.Context, tx *sql.Tx, before models.Event) error {
+	event, err := scanEvent(tx.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events e WHERE e.id = $1", before.ID))
+	if err != nil {
+		return classify(err)
+	}
+	if err := addOutbox(ctx, tx, bus.EventUpdated, bus.AggregateEvent, event.ID, event); err != nil {
+		return err
+	}
+	if event.SameSchedule(before) {
+		return nil
+	}
+	return addOutbox(ctx, tx, bus.EventRescheduled, bus.AggregateEvent, event.ID, event)
+}
+
+// bookingStatusMessages — тип сообщения для каждого статуса, в который бронирование переводит SetBookingStatus.
+var bookingStatusMessages = map[string]string{
+	models.BookingConfirmed: bus.BookingConfirmed,
+	models.BookingCancelled: bus.BookingCancelled,
+	models.BookingAttended:  bus.BookingAttended,
+}
+
+func (s *Storage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) (_ []models.OutboxMessage, err error) {
+	const op = "storage.postgre.ClaimOutbox"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	// NOT EXISTS пропускает агрегаты, у которых есть более раннее сообщение — в аренде, в ожидании повтора
+	// или ещё не доставленное, кроме недоставляемых (failed_at); SKIP LOCKED — строки, которые прямо сейчас
+	// берёт другой диспетчер
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE outbox SET locked_until = now() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT o.id FROM outbox o
+			WHERE o.failed_at IS NULL AND o.next_attempt_at <= now() AND (o.locked_until IS NULL OR o.locked_until <= now())
+				AND NOT EXISTS (SELECT 1 FROM outbox p
+					WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id AND p.id < o.id
+						AND p.failed_at IS NULL)
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, aggregate_type, aggregate_id, payload, created_at, attempts`,
+		limit, lease.Milliseconds(),
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to claim outbox messages", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+		}
+	}()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var msg models.OutboxMessage
+		err := rows.Scan(&msg.ID, &msg.Type, &msg.AggregateType, &msg.AggregateID, &msg.Payload, &msg.CreatedAt, &msg.Attempts)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan outbox message", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	// RETURNING порядок не гарантирует
+	slices.SortFunc(messages, func(a, b models.OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
+
+func (s *Storage) AckOutbox(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgre.AckOutbox"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = $1", id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete outbox message", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) RetryOutbox(ctx context.Context, id int64, next time.Time, reason string) (err error) {
+	const op = "storage.postgre.RetryOutbox"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, locked_until = NULL WHERE id = $3",
+		reason, next, id,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to reschedule outbox message", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) FailOutbox(ctx context.Context, id int64, reason string) (err error) {
+	const op = "storage.postgre.FailOutbox"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = $1, failed_at = now(), locked_until = NULL WHERE id = $2",
+		reason, id,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to mark outbox message as failed", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
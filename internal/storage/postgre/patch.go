@@ -0,0 +1,181 @@
+package postgre
+
+import (
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// assignments собирает SET-часть частичного UPDATE: только колонки, заданные в патче.
+type assignments struct {
+	columns []string
+	args    []any
+}
+
+// add добавляет "column = $n"; column — только константа из кода.
+func (a *assignments) add(column string, value any) {
+	a.args = append(a.args, value)
+	a.columns = append(a.columns, fmt.Sprintf("%s = $%d", column, len(a.args)))
+}
+
+// update строит UPDATE table по id с проверкой версии (0 — без проверки) и увеличением версии.
+// table может быть с псевдонимом ("users AS u"), чтобы дописать RETURNING с колонками userColumns и т.п.
+// Пустой патч только увеличивает версию.
+func (a *assignments) update(table string, id, version int64) (string, []any) {
+	args := append(a.args, id, version)
+	set := append(a.columns, "version = version + 1")
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d AND ($%d::bigint = 0 OR version = $%d)",
+		table, strings.Join(set, ", "), len(args)-1, len(args), len(args))
+	return query, args
+}
+
+func (s *Storage) PatchUser(ctx context.Context, id, version int64, patch storage.UserPatch) (err error) {
+	const op = "storage.postgre.PatchUser"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var set assignments
+	if patch.Name != nil {
+		set.add("name", *patch.Name)
+	}
+	if patch.Email != nil {
+		set.add("email", *patch.Email)
+	}
+	if patch.Role != nil {
+		set.add("role", *patch.Role)
+	}
+	query, args := set.update("users AS u", id, version)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		updated, err := scanUser(tx.QueryRowContext(ctx, query+" RETURNING "+userColumns, args...))
+		if err == sql.ErrNoRows {
+			return s.staleOrMissing(ctx, "users", id)
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to patch user", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return userOutbox(ctx, tx, bus.UserUpdated, updated)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) PatchEvent(ctx context.Context, id, version int64, patch storage.EventPatch) (err error) {
+	const op = "storage.postgre.PatchEvent"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var set assignments
+	if patch.Title != nil {
+		set.add("title", *patch.Title)
+	}
+	if patch.Description != nil {
+		set.add("description", *patch.Description)
+	}
+	if patch.StartAt != nil {
+		set.add("start_at", *patch.StartAt)
+	}
+	if patch.EndAt != nil {
+		set.add("end_at", *patch.EndAt)
+	}
+	if patch.TimeZone != nil {
+		set.add("time_zone", *patch.TimeZone)
+	}
+	if patch.Capacity != nil {
+		set.add("capacity", *patch.Capacity)
+	}
+
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// блокировка события — как в UpdateEvent
+		st, err := lockEvent(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && st.version != version {
+			return storage.ErrVersionMismatch
+		}
+		if patch.Capacity != nil && *patch.Capacity < st.booked {
+			return fmt.Errorf("capacity %d is less than %d booked seats: %w", *patch.Capacity, st.booked, storage.ErrConflict)
+		}
+		before, err := eventSchedule(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		query, args := set.update("events", id, version)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			s.log.ErrorContext(ctx, "Failed to patch event", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if patch.Capacity != nil {
+			if promoted, err = promoteWaitlist(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		return eventUpdatedOutbox(ctx, tx, before)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.publishPromoted(ctx, promoted)
+	return nil
+}
+
+func (s *Storage) PatchBooking(ctx context.Context, id, version int64, patch storage.BookingPatch) (err error) {
+	const op = "storage.postgre.PatchBooking"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var set assignments
+	if patch.EventID != nil {
+		set.add("event_id", *patch.EventID)
+	}
+	if patch.UserID != nil {
+		set.add("user_id", *patch.UserID)
+	}
+
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		current, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && current.Version != version {
+			return storage.ErrVersionMismatch
+		}
+		if !current.Active() {
+			return fmt.Errorf("booking %d is %s: %w", id, current.Status, storage.ErrInvalidStatus)
+		}
+		moved := patch.EventID != nil && *patch.EventID != current.EventID
+		if moved {
+			if err := lockEvents(ctx, tx, current.EventID, *patch.EventID); err != nil {
+				return err
+			}
+			if err := reserveSeat(ctx, tx, *patch.EventID); err != nil {
+				return err
+			}
+		}
+		query, args := set.update("bookings AS b", id, version)
+		updated, err := scanBooking(tx.QueryRowContext(ctx, query+" RETURNING "+bookingColumns, args...))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to patch booking", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if err := bookingOutbox(ctx, tx, bus.BookingUpdated, updated); err != nil {
+			return err
+		}
+		if moved {
+			promoted, err = promoteWaitlist(ctx, tx, current.EventID)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.publishPromoted(ctx, promoted)
+	return nil
+}
@@ -1,31 +1,44 @@
 package postgre
 
 import (
+	"TRYREST/internal/bus"
 	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"time"
 
-	_ "github.com/lib/pq" // инициализация драйвера postgres
+	"github.com/lib/pq" // заодно регистрирует драйвер postgres
 )
 
+var _ storage.Storage = (*Storage)(nil)
+
 type Storage struct {
-	db  *sql.DB
-	log *slog.Logger
+	db           *sql.DB
+	log          *slog.Logger
+	bus          bus.Publisher // может быть nil
+	queryTimeout time.Duration // предел на одну операцию, см. begin
 }
 
-func New(dsn string, logger *slog.Logger) (*Storage, error) {
+// New подключается к БД. queryTimeout ограничивает каждую операцию хранилища
+// (запрос или транзакцию целиком) поверх ctx вызывающего; 0 — без собственного предела.
+func New(dsn string, queryTimeout time.Duration, logger *slog.Logger, publisher bus.Publisher) (*Storage, error) {
 	const op = "storage.postgres.New"
 
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 
 	//проверка подключения
 	if err := db.Ping(); err != nil {
 		slog.Error("Failed to ping database", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: ping: %w", op, err)
+		return nil, fmt.Errorf("%s: ping: %w: %w", op, storage.ErrUnavailable, err)
 	}
 
 	// напрямую, но так как появились миграции то ненужно
@@ -50,284 +63,651 @@ func New(dsn string, logger *slog.Logger) (*Storage, error) {
 	//`
 	//
 	//if _, err := db.Exec(createSQL); err != nil {
-	//	return nil, fmt.Errorf("%s: %w", op, err)
+	//	return nil, fmt.Errorf("%s: %w", op, classify(err))
 	//}
 
 	return &Storage{
-		db:  db,
-		log: logger,
+		db:           db,
+		log:          logger,
+		bus:          publisher,
+		queryTimeout: queryTimeout,
 	}, nil
 }
 
-func (s *Storage) GetAllUsers() ([]models.User, error) {
+func (s *Storage) GetAllUsers(ctx context.Context, filter storage.UserFilter) (_ []models.User, err error) {
 	const op = "storage.postgre.GetAllUsers"
-	rows, err := s.db.Query("SELECT id, name, email FROM users")
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	query := "SELECT " + userColumns + " FROM users u WHERE true"
+	var args []any
+	if filter.Email != "" {
+		args = append(args, likePattern(filter.Email))
+		query += fmt.Sprintf(" AND u.email ILIKE $%d", len(args))
+	}
+	query, args = keyset(query, args, filter.Page, userSortColumns, "u.id")
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		s.log.Error("Failed to query users", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query users", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
-			s.log.Error("Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
 		}
 	}()
 
 	var users []models.User
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email); err != nil {
-			s.log.Error("Failed to scan user", slog.String("op", op), slog.Any("error", err))
-			return nil, fmt.Errorf("%s: %w", op, err)
+		user, err := scanUser(rows)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan user", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
 		}
 		users = append(users, user)
 	}
 	if err := rows.Err(); err != nil {
-		s.log.Error("Error iterating rows", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return users, nil
 }
 
-func (s *Storage) GetUserByID(id int64) (models.User, error) {
+func (s *Storage) GetUserByID(ctx context.Context, id int64) (_ models.User, err error) {
 	const op = "storage.postgre.GetUserByID"
-	var user models.User
-	err := s.db.QueryRow("SELECT id, name, email FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Email)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users u WHERE u.id = $1", id))
 	if err == sql.ErrNoRows {
-		return models.User{}, fmt.Errorf("%s: user not found", op)
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
 	}
 	if err != nil {
-		s.log.Error("Failed to query user by ID", slog.String("op", op), slog.Any("error", err))
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query user by ID", slog.String("op", op), slog.Any("error", err))
+		return models.User{}, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return user, nil
 }
 
-func (s *Storage) AddUser(name, email string) (int64, error) {
+func (s *Storage) GetUserByEmail(ctx context.Context, email string) (_ models.User, err error) {
+	const op = "storage.postgre.GetUserByEmail"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users u WHERE u.email = $1", email))
+	if err == sql.ErrNoRows {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query user by email", slog.String("op", op), slog.Any("error", err))
+		return models.User{}, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return user, nil
+}
+
+func (s *Storage) AddUser(ctx context.Context, user models.User) (_ int64, err error) {
 	const op = "storage.postgres.AddUser"
-	var id int64
-	// используем QueryRow + RETURNING id
-	err := s.db.QueryRow("INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id", name, email).Scan(&id)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		created, err := scanUser(tx.QueryRowContext(ctx,
+			"INSERT INTO users AS u (name, email, password_hash, role) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING "+userColumns,
+			user.Name, user.Email, user.PasswordHash, user.Role,
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert user", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		user = created
+		return userOutbox(ctx, tx, bus.UserCreated, user)
+	})
 	if err != nil {
-		s.log.Error("Failed to insert user", slog.String("op", op), slog.Any("error", err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	return id, nil
+	return user.ID, nil
 }
 
-func (s *Storage) UpdateUser(id int64, name, email string) error {
+func (s *Storage) UpdateUser(ctx context.Context, user models.User) (err error) {
 	const op = "storage.postgre.UpdateUser"
-	result, err := s.db.Exec("UPDATE users SET name = $1, email = $2 WHERE id = $3", name, email, id)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// пустая роль — оставить текущую
+		updated, err := scanUser(tx.QueryRowContext(ctx,
+			`UPDATE users AS u SET name = $1, email = $2, role = COALESCE(NULLIF($3, ''), role), version = version + 1
+			WHERE id = $4 AND ($5::bigint = 0 OR version = $5) RETURNING `+userColumns,
+			user.Name, user.Email, user.Role, user.ID, user.Version,
+		))
+		if err == sql.ErrNoRows {
+			return s.staleOrMissing(ctx, "users", user.ID)
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update user", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return userOutbox(ctx, tx, bus.UserUpdated, updated)
+	})
 	if err != nil {
-		s.log.Error("Failed to update user", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, id, version int64) (err error) {
+	const op = "storage.postgre.DeleteUser"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// FOR UPDATE не даёт создать пользователю новое бронирование или холд, пока идёт удаление
+		deleted, err := scanUser(tx.QueryRowContext(ctx,
+			"SELECT "+userColumns+" FROM users u WHERE u.id = $1 FOR UPDATE", id,
+		))
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return classify(err)
+		}
+		if version != 0 && deleted.Version != version {
+			return storage.ErrVersionMismatch
+		}
+		// места пользователя освобождаем явно, а не каскадом: получателям outbox нужны BookingDeleted,
+		// а освободившиеся места — листу ожидания
+		events, err := deleteUserSeats(ctx, tx, id)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to release user seats", slog.String("op", op), slog.Any("error", err))
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete user", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if err := userOutbox(ctx, tx, bus.UserDeleted, deleted); err != nil {
+			return err
+		}
+		// события блокируются по возрастанию id, как в ReleaseExpiredHolds
+		for _, eventID := range slices.Sorted(maps.Keys(events)) {
+			p, err := promoteWaitlist(ctx, tx, eventID)
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, p...)
+		}
+		return nil
+	})
 	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: user not found", op)
-	}
+	s.publishPromoted(ctx, promoted)
 	return nil
 }
 
-func (s *Storage) DeleteUser(id int64) error {
-	const op = "storage.postgre.DeleteUser"
-	result, err := s.db.Exec("DELETE FROM users WHERE id = $1", id)
+// deleteUserSeats удаляет бронирования, холды и очередь пользователя, пишет BookingDeleted по каждому
+// бронированию и возвращает события, на которых освободились места. Очередь удаляется до продвижения,
+// чтобы место не досталось самому удаляемому пользователю.
+func deleteUserSeats(ctx context.Context, tx *sql.Tx, userID int64) (map[int64]bool, error) {
+	rows, err := tx.QueryContext(ctx, "DELETE FROM bookings AS b WHERE user_id = $1 RETURNING "+bookingColumns, userID)
 	if err != nil {
-		s.log.Error("Failed to delete user", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, classify(err)
+	}
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			rows.Close()
+			return nil, classify(err)
+		}
+		bookings = append(bookings, booking)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, classify(err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	if err := rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+
+	events := make(map[int64]bool)
+	for _, booking := range bookings {
+		if err := bookingOutbox(ctx, tx, bus.BookingDeleted, booking); err != nil {
+			return nil, err
+		}
+		// отменённое бронирование место не занимало
+		if booking.Status != models.BookingCancelled {
+			events[booking.EventID] = true
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, "DELETE FROM holds WHERE user_id = $1 AND expires_at > now() RETURNING event_id", userID)
 	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, classify(err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: user not found", op)
+	for rows.Next() {
+		var eventID int64
+		if err := rows.Scan(&eventID); err != nil {
+			rows.Close()
+			return nil, classify(err)
+		}
+		events[eventID] = true
 	}
-	return nil
+	if err := rows.Close(); err != nil {
+		return nil, classify(err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM waitlist WHERE user_id = $1", userID); err != nil {
+		return nil, classify(err)
+	}
+	return events, nil
 }
 
-func (s *Storage) GetAllEvents() ([]models.Event, error) {
+func (s *Storage) GetAllEvents(ctx context.Context, filter storage.EventFilter) (_ []models.Event, err error) {
 	const op = "storage.postgre.GetAllEvents"
-	rows, err := s.db.Query("SELECT id, title, description FROM events")
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	query := "SELECT " + eventColumns + " FROM events e WHERE true"
+	var args []any
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(" AND e.start_at >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(" AND e.start_at < $%d", len(args))
+	}
+	if filter.Title != "" {
+		args = append(args, likePattern(filter.Title))
+		query += fmt.Sprintf(" AND e.title ILIKE $%d", len(args))
+	}
+	query, args = keyset(query, args, filter.Page, eventSortColumns, "e.id")
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		s.log.Error("Failed to query events", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query events", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
-			s.log.Error("Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
 		}
 	}()
 
 	var events []models.Event
 	for rows.Next() {
-		var event models.Event
-		if err := rows.Scan(&event.ID, &event.Title, &event.Description); err != nil {
-			s.log.Error("Failed to scan event", slog.String("op", op), slog.Any("error", err))
-			return nil, fmt.Errorf("%s: %w", op, err)
+		event, err := scanEvent(rows)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan event", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
 		}
 		events = append(events, event)
 	}
 	if err := rows.Err(); err != nil {
-		s.log.Error("Error iterating rows", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return events, nil
 }
 
-func (s *Storage) GetEventByID(id int64) (models.Event, error) {
+func (s *Storage) GetEventByID(ctx context.Context, id int64) (_ models.Event, err error) {
 	const op = "storage.postgre.GetEventByID"
-	var event models.Event
-	err := s.db.QueryRow("SELECT id, title, description FROM events WHERE id = $1", id).Scan(&event.ID, &event.Title, &event.Description)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	event, err := scanEvent(s.db.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events e WHERE e.id = $1", id))
 	if err == sql.ErrNoRows {
-		return models.Event{}, fmt.Errorf("%s: event not found", op)
+		return models.Event{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
 	}
 	if err != nil {
-		s.log.Error("Failed to query event by ID", slog.String("op", op), slog.Any("error", err))
-		return models.Event{}, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query event by ID", slog.String("op", op), slog.Any("error", err))
+		return models.Event{}, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return event, nil
 }
 
-func (s *Storage) AddEvent(title, description string) (int64, error) {
+func (s *Storage) AddEvent(ctx context.Context, event models.Event) (_ int64, err error) {
 	const op = "storage.postgres.AddEvent"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
 	var id int64
-	err := s.db.QueryRow("INSERT INTO events (title, description) VALUES ($1, $2) RETURNING id", title, description).Scan(&id)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO events (title, description, start_at, end_at, time_zone, capacity, organizer_id)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0)) RETURNING id`,
+			event.Title, event.Description, event.StartAt, event.EndAt, event.TimeZone, event.Capacity, event.OrganizerID,
+		).Scan(&id)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert event", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return eventOutbox(ctx, tx, bus.EventCreated, id)
+	})
 	if err != nil {
-		s.log.Error("Failed to insert event", slog.String("op", op), slog.Any("error", err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	return id, nil
 }
 
-func (s *Storage) UpdateEvent(id int64, title, description string) error {
+func (s *Storage) UpdateEvent(ctx context.Context, event models.Event) (err error) {
 	const op = "storage.postgre.UpdateEvent"
-	result, err := s.db.Exec("UPDATE events SET title = $1, description = $2 WHERE id = $3", title, description, id)
-	if err != nil {
-		s.log.Error("Failed to update event", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
-	}
-	rowsAffected, err := result.RowsAffected()
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// блокируем событие, чтобы параллельные AddBooking не проскочили между подсчётом и обновлением
+		st, err := lockEvent(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		if event.Version != 0 && st.version != event.Version {
+			return storage.ErrVersionMismatch
+		}
+		if event.Capacity < st.booked {
+			return fmt.Errorf("capacity %d is less than %d booked seats: %w", event.Capacity, st.booked, storage.ErrConflict)
+		}
+		before, err := eventSchedule(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE events SET title = $1, description = $2, start_at = $3, end_at = $4, time_zone = $5, capacity = $6,
+			version = version + 1 WHERE id = $7`,
+			event.Title, event.Description, event.StartAt, event.EndAt, event.TimeZone, event.Capacity, event.ID,
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update event", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		// вместимость могла вырасти
+		promoted, err = promoteWaitlist(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		return eventUpdatedOutbox(ctx, tx, before)
+	})
 	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: event not found", op)
-	}
+	s.publishPromoted(ctx, promoted)
 	return nil
 }
 
-func (s *Storage) DeleteEvent(id int64) error {
+func (s *Storage) DeleteEvent(ctx context.Context, id, version int64) (err error) {
 	const op = "storage.postgre.DeleteEvent"
-	result, err := s.db.Exec("DELETE FROM events WHERE id = $1", id)
-	if err != nil {
-		s.log.Error("Failed to delete event", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
-	}
-	rowsAffected, err := result.RowsAffected()
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		st, err := lockEvent(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && st.version != version {
+			return storage.ErrVersionMismatch
+		}
+		// снимок — до удаления, пока бронирования ещё на месте
+		if err := eventOutbox(ctx, tx, bus.EventDeleted, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM events WHERE id = $1", id); err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete event", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return nil
+	})
 	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: event not found", op)
-	}
 	return nil
 }
 
-func (s *Storage) GetAllBookings() ([]models.Booking, error) {
+func (s *Storage) GetAllBookings(ctx context.Context, filter storage.BookingFilter) (_ []models.Booking, err error) {
 	const op = "storage.postgre.GetAllBookings"
-	rows, err := s.db.Query("SELECT id, event_id, user_id FROM bookings")
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	query := "SELECT " + bookingColumns + " FROM bookings b WHERE true"
+	var args []any
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND b.user_id = $%d", len(args))
+	}
+	if filter.EventID != 0 {
+		args = append(args, filter.EventID)
+		query += fmt.Sprintf(" AND b.event_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND b.status = $%d", len(args))
+	}
+	query, args = keyset(query, args, filter.Page, nil, "b.id")
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		s.log.Error("Failed to query bookings", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query bookings", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
-			s.log.Error("Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
 		}
 	}()
 
 	var bookings []models.Booking
 	for rows.Next() {
-		var booking models.Booking
-		if err := rows.Scan(&booking.ID, &booking.EventID, &booking.UserID); err != nil {
-			s.log.Error("Failed to scan booking", slog.String("op", op), slog.Any("error", err))
-			return nil, fmt.Errorf("%s: %w", op, err)
+		booking, err := scanBooking(rows)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan booking", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
 		}
 		bookings = append(bookings, booking)
 	}
 	if err := rows.Err(); err != nil {
-		s.log.Error("Error iterating rows", slog.String("op", op), slog.Any("error", err))
-		return nil, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return bookings, nil
 }
 
-func (s *Storage) GetBookingByID(id int64) (models.Booking, error) {
+func (s *Storage) GetBookingByID(ctx context.Context, id int64) (_ models.Booking, err error) {
 	const op = "storage.postgre.GetBookingByID"
-	var booking models.Booking
-	err := s.db.QueryRow("SELECT id, event_id, user_id FROM bookings WHERE id = $1", id).Scan(&booking.ID, &booking.EventID, &booking.UserID)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	booking, err := scanBooking(s.db.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings b WHERE b.id = $1", id))
 	if err == sql.ErrNoRows {
-		return models.Booking{}, fmt.Errorf("%s: booking not found", op)
+		return models.Booking{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
 	}
 	if err != nil {
-		s.log.Error("Failed to query booking by ID", slog.String("op", op), slog.Any("error", err))
-		return models.Booking{}, fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query booking by ID", slog.String("op", op), slog.Any("error", err))
+		return models.Booking{}, fmt.Errorf("%s: %w", op, classify(err))
 	}
 	return booking, nil
 }
 
-func (s *Storage) AddBooking(eventID, userID int64) (int64, error) {
+func (s *Storage) AddBooking(ctx context.Context, eventID, userID int64) (_ int64, err error) {
 	const op = "storage.postgres.AddBooking"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
 	var id int64
-	err := s.db.QueryRow("INSERT INTO bookings (event_id, user_id) VALUES ($1, $2) RETURNING id", eventID, userID).Scan(&id)
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		if err := reserveSeat(ctx, tx, eventID); err != nil {
+			return err
+		}
+		booking, err := scanBooking(tx.QueryRowContext(ctx,
+			"INSERT INTO bookings AS b (event_id, user_id) VALUES ($1, $2) RETURNING "+bookingColumns, eventID, userID,
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert booking", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		id = booking.ID
+		return bookingOutbox(ctx, tx, bus.BookingCreated, booking)
+	})
 	if err != nil {
-		s.log.Error("Failed to insert booking", slog.String("op", op), slog.Any("error", err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	return id, nil
 }
 
-func (s *Storage) UpdateBooking(id, eventID, userID int64) error {
+func (s *Storage) UpdateBooking(ctx context.Context, id, eventID, userID, version int64) (err error) {
 	const op = "storage.postgre.UpdateBooking"
-	result, err := s.db.Exec("UPDATE bookings SET event_id = $1, user_id = $2 WHERE id = $3", eventID, userID, id)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		current, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && current.Version != version {
+			return storage.ErrVersionMismatch
+		}
+		if !current.Active() {
+			return fmt.Errorf("booking %d is %s: %w", id, current.Status, storage.ErrInvalidStatus)
+		}
+		// место проверяем только при переносе брони на другое событие
+		if current.EventID != eventID {
+			if err := lockEvents(ctx, tx, current.EventID, eventID); err != nil {
+				return err
+			}
+			if err := reserveSeat(ctx, tx, eventID); err != nil {
+				return err
+			}
+		}
+		updated, err := scanBooking(tx.QueryRowContext(ctx,
+			"UPDATE bookings AS b SET event_id = $1, user_id = $2, version = version + 1 WHERE id = $3 RETURNING "+bookingColumns,
+			eventID, userID, id,
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update booking", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if err := bookingOutbox(ctx, tx, bus.BookingUpdated, updated); err != nil {
+			return err
+		}
+		if current.EventID != eventID {
+			promoted, err = promoteWaitlist(ctx, tx, current.EventID)
+		}
+		return err
+	})
 	if err != nil {
-		s.log.Error("Failed to update booking", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
+	s.publishPromoted(ctx, promoted)
+	return nil
+}
+
+func (s *Storage) SetBookingStatus(ctx context.Context, id int64, status string) (_ models.Booking, err error) {
+	const op = "storage.postgre.SetBookingStatus"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	column, ok := statusTimeColumns[status]
+	if !ok {
+		return models.Booking{}, fmt.Errorf("%s: unknown status %q: %w", op, status, storage.ErrInvalidStatus)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: booking not found", op)
+	var booking models.Booking
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		current, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !current.CanTransitionTo(status) {
+			return fmt.Errorf("booking %d: %s -> %s: %w", id, current.Status, status, storage.ErrInvalidStatus)
+		}
+		// column берётся из statusTimeColumns, а не от клиента
+		booking, err = scanBooking(tx.QueryRowContext(ctx,
+			"UPDATE bookings b SET status = $1, "+column+" = now(), version = version + 1 WHERE b.id = $2 RETURNING "+bookingColumns,
+			status, id,
+		))
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update booking status", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if err := bookingOutbox(ctx, tx, bookingStatusMessages[status], booking); err != nil {
+			return err
+		}
+		if status == models.BookingCancelled {
+			promoted, err = promoteWaitlist(ctx, tx, booking.EventID)
+		}
+		return err
+	})
+	if err != nil {
+		return models.Booking{}, fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+	s.publishPromoted(ctx, promoted)
+	return booking, nil
 }
 
-func (s *Storage) DeleteBooking(id int64) error {
+func (s *Storage) DeleteBooking(ctx context.Context, id, version int64) (err error) {
 	const op = "storage.postgre.DeleteBooking"
-	result, err := s.db.Exec("DELETE FROM bookings WHERE id = $1", id)
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var promoted []models.Booking
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		current, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && current.Version != version {
+			return storage.ErrVersionMismatch
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM bookings WHERE id = $1", id); err != nil {
+			s.log.ErrorContext(ctx, "Failed to delete booking", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		if err := bookingOutbox(ctx, tx, bus.BookingDeleted, current); err != nil {
+			return err
+		}
+		// отменённое бронирование место не занимало
+		if current.Status != models.BookingCancelled {
+			promoted, err = promoteWaitlist(ctx, tx, current.EventID)
+		}
+		return err
+	})
 	if err != nil {
-		s.log.Error("Failed to delete booking", slog.String("op", op), slog.Any("error", err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	s.publishPromoted(ctx, promoted)
+	return nil
+}
+
+func (s *Storage) GetRolePermissions(ctx context.Context) (_ map[string][]string, err error) {
+	const op = "storage.postgre.GetRolePermissions"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	// LEFT JOIN — роль без прав тоже должна попасть в результат
+	rows, err := s.db.QueryContext(ctx, "SELECT r.name, rp.permission FROM roles r LEFT JOIN role_permissions rp ON rp.role = r.name")
 	if err != nil {
-		s.log.Error("Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
-		return fmt.Errorf("%s: %w", op, err)
+		s.log.ErrorContext(ctx, "Failed to query role permissions", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: booking not found", op)
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+		}
+	}()
+
+	perms := make(map[string][]string)
+	for rows.Next() {
+		var role string
+		var perm sql.NullString
+		if err := rows.Scan(&role, &perm); err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan role permission", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
+		}
+		if perm.Valid {
+			perms[role] = append(perms[role], perm.String)
+		} else {
+			perms[role] = nil
+		}
 	}
-	return nil
+	if err := rows.Err(); err != nil {
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return perms, nil
+}
+
+// Stats — статистика пула соединений, для метрик.
+func (s *Storage) Stats() sql.DBStats {
+	return s.db.Stats()
 }
 
 func (s *Storage) Close() error {
@@ -337,3 +717,131 @@ func (s *Storage) Close() error {
 	// sql.DB.Close() закрывает пул соединений и освобождает ресурсы
 	return s.db.Close()
 }
+
+// userColumns — колонки пользователя для SELECT ... FROM users u; порядок совпадает со scanUser.
+const userColumns = `u.id, u.name, u.email, u.role, COALESCE(u.password_hash, ''), u.version`
+
+func scanUser(row rowScanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.PasswordHash, &user.Version)
+	return user, err
+}
+
+// eventColumns — колонки события для SELECT ... FROM events e; порядок совпадает со scanEvent.
+const eventColumns = `e.id, e.title, COALESCE(e.description, ''), e.start_at, e.end_at, e.time_zone, e.capacity,
+	COALESCE(e.organizer_id, 0), e.version,
+	e.capacity - (SELECT count(*) FROM bookings b WHERE b.event_id = e.id AND b.status <> 'cancelled')
+		- (SELECT count(*) FROM holds h WHERE h.event_id = e.id AND h.expires_at > now())`
+
+func scanEvent(row rowScanner) (models.Event, error) {
+	var event models.Event
+	err := row.Scan(&event.ID, &event.Title, &event.Description, &event.StartAt, &event.EndAt, &event.TimeZone,
+		&event.Capacity, &event.OrganizerID, &event.Version, &event.RemainingSeats)
+	return event, err
+}
+
+// bookingColumns — колонки бронирования для SELECT ... FROM bookings b; порядок совпадает со scanBooking.
+const bookingColumns = `b.id, b.event_id, b.user_id, b.status, b.created_at, b.confirmed_at, b.cancelled_at, b.attended_at,
+	b.version`
+
+func scanBooking(row rowScanner) (models.Booking, error) {
+	var booking models.Booking
+	err := row.Scan(&booking.ID, &booking.EventID, &booking.UserID, &booking.Status,
+		&booking.CreatedAt, &booking.ConfirmedAt, &booking.CancelledAt, &booking.AttendedAt, &booking.Version)
+	return booking, err
+}
+
+// statusTimeColumns — колонка с временем перехода в каждый статус, кроме начального.
+var statusTimeColumns = map[string]string{
+	models.BookingConfirmed: "confirmed_at",
+	models.BookingCancelled: "cancelled_at",
+	models.BookingAttended:  "attended_at",
+}
+
+// lockBooking блокирует строку бронирования до конца транзакции и возвращает её.
+func lockBooking(ctx context.Context, tx *sql.Tx, id int64) (models.Booking, error) {
+	booking, err := scanBooking(tx.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings b WHERE b.id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return models.Booking{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Booking{}, classify(err)
+	}
+	return booking, nil
+}
+
+// seats — занятость события, прочитанная под блокировкой.
+type seats struct {
+	version  int64
+	capacity int
+	booked   int  // неотменённые бронирования и действующие холды
+	started  bool // start_at <= now() по часам БД
+}
+
+// lockEvent блокирует строку события до конца транзакции (SELECT ... FOR UPDATE)
+// и возвращает его занятость. Все операции, меняющие занятость события,
+// проходят через эту блокировку, поэтому параллельные брони
+// не могут одновременно занять последнее место.
+func lockEvent(ctx context.Context, tx *sql.Tx, eventID int64) (seats, error) {
+	var st seats
+	err := tx.QueryRowContext(ctx, "SELECT version, capacity, start_at <= now() FROM events WHERE id = $1 FOR UPDATE", eventID).
+		Scan(&st.version, &st.capacity, &st.started)
+	if err == sql.ErrNoRows {
+		return seats{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return seats{}, classify(err)
+	}
+	err = tx.QueryRowContext(ctx, `SELECT (SELECT count(*) FROM bookings WHERE event_id = $1 AND status <> 'cancelled')
+		+ (SELECT count(*) FROM holds WHERE event_id = $1 AND expires_at > now())`, eventID).Scan(&st.booked)
+	if err != nil {
+		return seats{}, classify(err)
+	}
+	return st, nil
+}
+
+// lockEvents блокирует строки нескольких событий в порядке возрастания id. Транзакция, которой
+// нужны два события (перенос брони), берёт их заранее: иначе встречный перенос в обратную сторону
+// блокирует их в обратном порядке, и обе транзакции ждут друг друга. Последующий lockEvent
+// по уже заблокированной строке не ждёт. Отсутствующие события пропускаются — их обработает lockEvent.
+func lockEvents(ctx context.Context, tx *sql.Tx, ids ...int64) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM events WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(ids))
+	if err != nil {
+		return classify(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return classify(rows.Err())
+}
+
+// reserveSeat проверяет под блокировкой события, что оно ещё не началось и на нём есть свободное место.
+func reserveSeat(ctx context.Context, tx *sql.Tx, eventID int64) error {
+	st, err := lockEvent(ctx, tx, eventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrForeignKey)
+	}
+	if err != nil {
+		return err
+	}
+	if st.started {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrEventStarted)
+	}
+	if st.booked >= st.capacity {
+		return fmt.Errorf("event %d: %w", eventID, storage.ErrEventFull)
+	}
+	return nil
+}
+
+// staleOrMissing объясняет, почему UPDATE или DELETE с проверкой версии не затронул строку table:
+// записи нет (ErrNotFound) или её версия уже другая (ErrVersionMismatch). table — только константа из кода.
+func (s *Storage) staleOrMissing(ctx context.Context, table string, id int64) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1)", id).Scan(&exists); err != nil {
+		return classify(err)
+	}
+	if exists {
+		return storage.ErrVersionMismatch
+	}
+	return storage.ErrNotFound
+}
@@ -0,0 +1,179 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+func (s *Storage) ScheduleReminders(ctx context.Context, bookingID int64, offsets []time.Duration) (_ int, err error) {
+	const op = "storage.postgre.ScheduleReminders"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	created, err := s.scheduleReminders(ctx, op, "b.id", bookingID, offsets)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return created, nil
+}
+
+func (s *Storage) RescheduleEventReminders(ctx context.Context, eventID int64, offsets []time.Duration) (_ int, err error) {
+	const op = "storage.postgre.RescheduleEventReminders"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	created, err := s.scheduleReminders(ctx, op, "b.event_id", eventID, offsets)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return created, nil
+}
+
+// scheduleReminders пересчитывает и создаёт напоминания бронирований, у которых column = id.
+// column — только константа из кода.
+func (s *Storage) scheduleReminders(ctx context.Context, op, column string, id int64, offsets []time.Duration) (int, error) {
+	seconds := make([]int64, len(offsets))
+	for i, offset := range offsets {
+		seconds[i] = int64(offset / time.Second)
+	}
+	var created int64
+	err := s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// событие могли перенести: срок ожидающих напоминаний считается от нового начала
+		_, err := tx.ExecContext(ctx,
+			`UPDATE reminders r SET due_at = e.start_at - r.offset_seconds * INTERVAL '1 second'
+			FROM bookings b JOIN events e ON e.id = b.event_id
+			WHERE r.booking_id = b.id AND r.status = 'pending' AND `+column+` = $1`,
+			id,
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to reschedule reminders", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO reminders (booking_id, offset_seconds, due_at)
+			SELECT b.id, o.seconds, e.start_at - o.seconds * INTERVAL '1 second'
+			FROM bookings b JOIN events e ON e.id = b.event_id, unnest($2::bigint[]) AS o(seconds)
+			WHERE `+column+` = $1 AND b.status IN ('pending', 'confirmed')
+				AND e.start_at - o.seconds * INTERVAL '1 second' > now()
+			ON CONFLICT (booking_id, offset_seconds) DO NOTHING`,
+			id, pq.Array(seconds),
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert reminders", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		created, err = res.RowsAffected()
+		return classify(err)
+	})
+	return int(created), err
+}
+
+func (s *Storage) CancelReminders(ctx context.Context, bookingID int64) (_ int, err error) {
+	const op = "storage.postgre.CancelReminders"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE reminders SET status = 'cancelled', locked_until = NULL WHERE booking_id = $1 AND status = 'pending'",
+		bookingID,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to cancel reminders", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	cancelled, err := res.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return int(cancelled), nil
+}
+
+func (s *Storage) ClaimReminders(ctx context.Context, limit int, lease time.Duration) (_ []models.Reminder, err error) {
+	const op = "storage.postgre.ClaimReminders"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	// SKIP LOCKED — напоминания, которые прямо сейчас берёт другой инстанс. Устаревшие отменяются
+	// тем же запросом: из нескольких наступивших у бронирования остаётся ближайшее к началу события
+	rows, err := s.db.QueryContext(ctx,
+		`WITH due AS (
+			SELECT q.id, b.status IN ('pending', 'confirmed') AND e.start_at > now() AND NOT EXISTS (
+				SELECT 1 FROM reminders n
+				WHERE n.booking_id = q.booking_id AND n.status = 'pending' AND n.due_at <= now()
+					AND n.offset_seconds < q.offset_seconds
+			) AS live
+			FROM reminders q JOIN bookings b ON b.id = q.booking_id JOIN events e ON e.id = b.event_id
+			WHERE q.status = 'pending' AND q.due_at <= now() AND (q.locked_until IS NULL OR q.locked_until <= now())
+			ORDER BY q.due_at, q.id
+			LIMIT $1
+			FOR UPDATE OF q SKIP LOCKED
+		)
+		UPDATE reminders AS r SET
+			status = CASE WHEN due.live THEN r.status ELSE 'cancelled' END,
+			locked_until = CASE WHEN due.live THEN now() + $2 * INTERVAL '1 millisecond' END
+		FROM due WHERE r.id = due.id
+		RETURNING due.live, `+reminderColumns,
+		limit, lease.Milliseconds(),
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to claim reminders", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+		}
+	}()
+
+	var reminders []models.Reminder
+	for rows.Next() {
+		var live bool
+		var r models.Reminder
+		var offset int64
+		if err := rows.Scan(&live, &r.ID, &r.BookingID, &offset, &r.DueAt, &r.Status, &r.SentAt); err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan reminder", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
+		}
+		if live {
+			r.Offset = time.Duration(offset) * time.Second
+			reminders = append(reminders, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return reminders, nil
+}
+
+func (s *Storage) CompleteReminder(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgre.CompleteReminder"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	// бронирование могли отменить, пока письмо собиралось: отменённое напоминание не трогаем
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE reminders SET status = 'sent', sent_at = now(), locked_until = NULL WHERE id = $1 AND status = 'pending'",
+		id,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to complete reminder", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// reminderColumns — колонки напоминания для RETURNING ... reminders r; порядок совпадает со сканированием в ClaimReminders.
+const reminderColumns = `r.id, r.booking_id, r.offset_seconds, r.due_at, r.status, r.sent_at`
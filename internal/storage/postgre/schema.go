@@ -0,0 +1,30 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgre.Ping"
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return nil
+}
+
+// SchemaVersion читает состояние миграций из таблицы schema_migrations, которую ведёт golang-migrate.
+func (s *Storage) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	const op = "storage.postgre.SchemaVersion"
+	var version uint
+	var dirty bool
+	err := s.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil // ни одной миграции ещё не применено
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return version, dirty, nil
+}
@@ -0,0 +1,122 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/handlers"
+	"TRYREST/internal/migrator"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/storage/storagetest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// openTestDB подключается к базе из TEST_DATABASE_URL и применяет миграции; без переменной тест пропускается.
+// Таблицы очищаются перед каждым хранилищем, поэтому база должна быть отдельной, только для тестов.
+func openTestDB(t *testing.T) (dsn string, log *slog.Logger) {
+	t.Helper()
+	dsn = os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	m, err := migrator.New(dsn, log)
+	if err != nil {
+		t.Fatalf("migrator.New: %v", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return dsn, log
+}
+
+// newTestStorage возвращает хранилище над пустыми таблицами; роли из миграций остаются.
+func newTestStorage(t *testing.T, dsn string, queryTimeout time.Duration, log *slog.Logger) *Storage {
+	t.Helper()
+	s, err := New(dsn, queryTimeout, log, bus.New())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	// конкурентные тесты запускают сотни горутин: без предела пул упрётся в max_connections сервера
+	s.db.SetMaxOpenConns(20)
+	// каскад очищает бронирования, холды, лист ожидания, ключи, напоминания и доставки
+	if _, err := s.db.Exec("TRUNCATE users, events, outbox, webhooks RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	dsn, log := openTestDB(t)
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t, dsn, 5*time.Second, log)
+	})
+}
+
+// TestQueryTimeout: запрос дольше queryTimeout отменяется БД, а ошибка несёт context.DeadlineExceeded.
+func TestQueryTimeout(t *testing.T) {
+	dsn, log := openTestDB(t)
+	s := newTestStorage(t, dsn, 50*time.Millisecond, log)
+	ctx, end := s.begin(context.Background(), "storage.postgre.TestQueryTimeout")
+	_, err := s.db.ExecContext(ctx, "SELECT pg_sleep(5)")
+	end(&err)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// sleepingEvents — хранилище, у которого чтение события выполняет долгий запрос к БД.
+type sleepingEvents struct {
+	*Storage
+}
+
+func (s sleepingEvents) GetEventByID(ctx context.Context, id int64) (_ models.Event, err error) {
+	ctx, end := s.begin(ctx, "storage.postgre.TestCancel")
+	defer end(&err)
+	_, err = s.db.ExecContext(ctx, "SELECT pg_sleep(5)")
+	return models.Event{}, err
+}
+
+// TestCancelDuringQuery: клиент отключился во время запроса — драйвер отменяет его в БД,
+// хранилище сразу возвращает context.Canceled, а обработчик отвечает 499.
+func TestCancelDuringQuery(t *testing.T) {
+	dsn, log := openTestDB(t)
+	s := sleepingEvents{newTestStorage(t, dsn, 5*time.Second, log)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := s.GetEventByID(ctx, 1)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetEventByID returned after %v, want right after cancellation", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/events/{id}", handlers.NewEventHandler(s).GetEventByID)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	rec := httptest.NewRecorder()
+	start = time.Now()
+	router.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/events/1", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("handler returned after %v, want right after cancellation", elapsed)
+	}
+	if rec.Code != 499 {
+		t.Errorf("status = %d, want 499", rec.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"TRYREST/internal/storage"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer берётся из глобального провайдера, поэтому подхватывает настройку tracing.Setup, сделанную позже.
+var tracer = otel.Tracer("TRYREST/internal/storage/postgre")
+
+// begin готовит ctx операции op: ограничивает её queryTimeout и открывает дочерний спан.
+// Возвращённую end вызывают через defer с адресом именованной ошибки метода. Если ошибка вызвана
+// отменой или таймаутом ctx, end добавляет в цепочку context.Canceled или context.DeadlineExceeded:
+// lib/pq сообщает об отмене своей ошибкой 57014, по которой причину не узнать.
+func (s *Storage) begin(ctx context.Context, op string) (context.Context, func(err *error)) {
+	cancel := context.CancelFunc(func() {})
+	if s.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.queryTimeout)
+	}
+	ctx, span := startSpan(ctx, op)
+	return ctx, func(err *error) {
+		if *err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(*err, ctxErr) {
+				*err = fmt.Errorf("%w (%w)", *err, ctxErr)
+			}
+		}
+		endSpan(span, err)
+		cancel()
+	}
+}
+
+// startSpan открывает дочерний спан запроса к БД с именем op (storage.postgre.GetUserByID).
+func startSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL),
+	)
+}
+
+// endSpan закрывает спан, записав в него ошибку метода. Ожидаемые исходы (нет записи, нет мест,
+// конфликт версий) и отключение клиента не помечают спан как ошибочный — это не сбой БД.
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		if !isExpected(*err) {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+	}
+	span.End()
+}
+
+func isExpected(err error) bool {
+	for _, target := range []error{
+		storage.ErrNotFound, storage.ErrConflict, storage.ErrForeignKey, storage.ErrEventFull,
+		storage.ErrEventStarted, storage.ErrEventNotFull, storage.ErrHoldExpired,
+		storage.ErrInvalidStatus, storage.ErrVersionMismatch, context.Canceled,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
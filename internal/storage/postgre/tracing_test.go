@@ -0,0 +1,60 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// errCanceled — так lib/pq сообщает об отменённом запросе (57014), без причины отмены.
+var errCanceled = errors.New("pq: canceling statement due to user request")
+
+func TestBeginAddsContextCause(t *testing.T) {
+	tests := []struct {
+		name         string
+		queryTimeout time.Duration
+		parent       func() (context.Context, context.CancelFunc)
+		want         error
+	}{
+		{
+			name:         "query timeout",
+			queryTimeout: 10 * time.Millisecond,
+			parent:       func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			want:         context.DeadlineExceeded,
+		},
+		{
+			name: "caller cancelled",
+			parent: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			want: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, cancel := tt.parent()
+			defer cancel()
+			s := &Storage{queryTimeout: tt.queryTimeout}
+			ctx, end := s.begin(parent, "storage.postgre.Test")
+			<-ctx.Done()
+			err := errCanceled
+			end(&err)
+			if !errors.Is(err, tt.want) || !errors.Is(err, errCanceled) {
+				t.Errorf("err = %v, want it to wrap %v and the driver error", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeginKeepsUnrelatedErrors(t *testing.T) {
+	s := &Storage{queryTimeout: time.Minute}
+	_, end := s.begin(context.Background(), "storage.postgre.Test")
+	err := errCanceled
+	end(&err)
+	if err != errCanceled {
+		t.Errorf("err = %v, want it unchanged", err)
+	}
+}
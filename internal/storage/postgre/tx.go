@@ -0,0 +1,33 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+// inTx выполняет fn в транзакции: коммитит, если fn вернула nil, иначе откатывает.
+// Ошибка fn возвращается как есть, чтобы вызывающий мог обернуть её своим op.
+func (s *Storage) inTx(ctx context.Context, op string, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to begin transaction", slog.String("op", op), slog.Any("error", err))
+		return classify(err)
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			s.log.ErrorContext(ctx, "Failed to rollback transaction", slog.String("op", op), slog.Any("error", rbErr))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to commit transaction", slog.String("op", op), slog.Any("error", err))
+		return classify(err)
+	}
+	return nil
+}
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
@@ -0,0 +1,173 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+func (s *Storage) JoinWaitlist(ctx context.Context, eventID, userID int64) (_ models.WaitlistEntry, err error) {
+	const op = "storage.postgre.JoinWaitlist"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var entry models.WaitlistEntry
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		// под блокировкой события: никто не освободит место между проверкой и вставкой
+		st, err := lockEvent(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		if st.started {
+			return fmt.Errorf("event %d: %w", eventID, storage.ErrEventStarted)
+		}
+		if st.booked < st.capacity {
+			return fmt.Errorf("event %d: %w", eventID, storage.ErrEventNotFull)
+		}
+		booked, err := hasActiveBooking(ctx, tx, eventID, userID)
+		if err != nil {
+			return err
+		}
+		if booked {
+			return fmt.Errorf("user %d already booked event %d: %w", userID, eventID, storage.ErrConflict)
+		}
+
+		var id int64
+		err = tx.QueryRowContext(ctx, "INSERT INTO waitlist (event_id, user_id) VALUES ($1, $2) RETURNING id", eventID, userID).Scan(&id)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to insert waitlist entry", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		entry, err = scanWaitlistEntry(tx.QueryRowContext(ctx, "SELECT "+waitlistColumns+" FROM waitlist w WHERE w.id = $1", id))
+		return classify(err)
+	})
+	if err != nil {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return entry, nil
+}
+
+func (s *Storage) GetWaitlistEntry(ctx context.Context, eventID, userID int64) (_ models.WaitlistEntry, err error) {
+	const op = "storage.postgre.GetWaitlistEntry"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	entry, err := scanWaitlistEntry(s.db.QueryRowContext(ctx,
+		"SELECT "+waitlistColumns+" FROM waitlist w WHERE w.event_id = $1 AND w.user_id = $2", eventID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return models.WaitlistEntry{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query waitlist entry", slog.String("op", op), slog.Any("error", err))
+		return models.WaitlistEntry{}, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return entry, nil
+}
+
+func (s *Storage) LeaveWaitlist(ctx context.Context, eventID, userID int64) (err error) {
+	const op = "storage.postgre.LeaveWaitlist"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM waitlist WHERE event_id = $1 AND user_id = $2", eventID, userID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete waitlist entry", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// waitlistColumns — колонки записи очереди для SELECT ... FROM waitlist w; порядок совпадает со scanWaitlistEntry.
+const waitlistColumns = `w.id, w.event_id, w.user_id, w.created_at,
+	(SELECT count(*) FROM waitlist o WHERE o.event_id = w.event_id AND o.id <= w.id)`
+
+func scanWaitlistEntry(row rowScanner) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := row.Scan(&entry.ID, &entry.EventID, &entry.UserID, &entry.CreatedAt, &entry.Position)
+	return entry, err
+}
+
+// hasActiveBooking сообщает, есть ли у пользователя неотменённое бронирование на событие.
+func hasActiveBooking(ctx context.Context, tx *sql.Tx, eventID, userID int64) (bool, error) {
+	var booked bool
+	err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM bookings WHERE event_id = $1 AND user_id = $2 AND status <> 'cancelled')",
+		eventID, userID,
+	).Scan(&booked)
+	return booked, classify(err)
+}
+
+// promoteWaitlist отдаёт свободные места события первым в очереди, создавая им бронирования в статусе pending.
+// Блокирует событие через lockEvent, поэтому параллельные отмены выполняются по очереди
+// и каждая видит места, уже розданные предыдущей. Для начавшихся событий ничего не делает.
+func promoteWaitlist(ctx context.Context, tx *sql.Tx, eventID int64) ([]models.Booking, error) {
+	st, err := lockEvent(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if st.started {
+		return nil, nil
+	}
+
+	var promoted []models.Booking
+	for free := st.capacity - st.booked; free > 0; {
+		var userID int64
+		err := tx.QueryRowContext(ctx,
+			"DELETE FROM waitlist WHERE id = (SELECT id FROM waitlist WHERE event_id = $1 ORDER BY id LIMIT 1) RETURNING user_id",
+			eventID,
+		).Scan(&userID)
+		if err == sql.ErrNoRows {
+			break // очередь пуста
+		}
+		if err != nil {
+			return nil, classify(err)
+		}
+		// пользователь мог забронировать место напрямую, пока стоял в очереди
+		booked, err := hasActiveBooking(ctx, tx, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if booked {
+			continue
+		}
+		booking, err := scanBooking(tx.QueryRowContext(ctx,
+			"INSERT INTO bookings AS b (event_id, user_id) VALUES ($1, $2) RETURNING "+bookingColumns, eventID, userID,
+		))
+		if err != nil {
+			return nil, classify(err)
+		}
+		if err := bookingOutbox(ctx, tx, bus.BookingCreated, booking); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, booking)
+		free--
+	}
+	return promoted, nil
+}
+
+// publishPromoted сообщает о бронированиях, выданных из очереди. Вызывать только после коммита.
+func (s *Storage) publishPromoted(ctx context.Context, bookings []models.Booking) {
+	for _, booking := range bookings {
+		s.log.InfoContext(ctx, "waitlist promoted",
+			slog.Int64("booking_id", booking.ID), slog.Int64("event_id", booking.EventID), slog.Int64("user_id", booking.UserID))
+		if s.bus != nil {
+			s.bus.Publish(bus.Message{
+				Type:          bus.WaitlistPromoted,
+				AggregateType: "booking",
+				AggregateID:   booking.ID,
+				Payload:       booking,
+			})
+		}
+	}
+}
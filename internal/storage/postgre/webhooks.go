@@ -0,0 +1,293 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+func (s *Storage) AddWebhook(ctx context.Context, webhook models.Webhook) (_ models.Webhook, err error) {
+	const op = "storage.postgre.AddWebhook"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	created, err := scanWebhook(s.db.QueryRowContext(ctx,
+		"INSERT INTO webhooks AS w (url, event_types, secret) VALUES ($1, $2, $3) RETURNING "+webhookColumns,
+		webhook.URL, pq.Array(webhook.EventTypes), webhook.Secret,
+	))
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to insert webhook", slog.String("op", op), slog.Any("error", err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return created, nil
+}
+
+func (s *Storage) GetAllWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	const op = "storage.postgre.GetAllWebhooks"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks w ORDER BY w.id")
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query webhooks", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+		}
+	}()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan webhook", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return webhooks, nil
+}
+
+func (s *Storage) GetWebhookByID(ctx context.Context, id int64) (_ models.Webhook, err error) {
+	const op = "storage.postgre.GetWebhookByID"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks w WHERE w.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query webhook by ID", slog.String("op", op), slog.Any("error", err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return webhook, nil
+}
+
+func (s *Storage) EnableWebhook(ctx context.Context, id int64) (_ models.Webhook, err error) {
+	const op = "storage.postgre.EnableWebhook"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var webhook models.Webhook
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		webhook, err = scanWebhook(tx.QueryRowContext(ctx,
+			"UPDATE webhooks AS w SET active = TRUE, consecutive_failures = 0, disabled_at = NULL WHERE w.id = $1 RETURNING "+webhookColumns,
+			id,
+		))
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to enable webhook", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		// отложенные за время сбоев повторы не ждут своего backoff
+		_, err = tx.ExecContext(ctx,
+			"UPDATE webhook_deliveries SET next_attempt_at = now() WHERE webhook_id = $1 AND status = 'pending'", id)
+		return classify(err)
+	})
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhook, nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) (err error) {
+	const op = "storage.postgre.DeleteWebhook"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to delete webhook", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) EnqueueWebhookDeliveries(ctx context.Context, msg models.OutboxMessage, payload []byte) (_ int, err error) {
+	const op = "storage.postgre.EnqueueWebhookDeliveries"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, message_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks WHERE active AND $2 = ANY (event_types)
+		ON CONFLICT (webhook_id, message_id) DO NOTHING`,
+		msg.ID, msg.Type, payload,
+	)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to enqueue webhook deliveries", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check rows affected", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return int(rowsAffected), nil
+}
+
+func (s *Storage) GetWebhookDeliveries(ctx context.Context, filter storage.WebhookDeliveryFilter) (_ []models.WebhookDelivery, err error) {
+	const op = "storage.postgre.GetWebhookDeliveries"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries d WHERE d.webhook_id = $1"
+	args := []any{filter.WebhookID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	}
+	query, args = keyset(query, args, filter.Page, nil, "d.id")
+	return s.queryDeliveries(ctx, op, query, args...)
+}
+
+func (s *Storage) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []models.WebhookDelivery, err error) {
+	const op = "storage.postgre.ClaimWebhookDeliveries"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	// SKIP LOCKED — доставки, которые прямо сейчас берёт другой инстанс
+	return s.queryDeliveries(ctx, op,
+		`UPDATE webhook_deliveries AS d SET locked_until = now() + $2 * INTERVAL '1 millisecond'
+		WHERE d.id IN (
+			SELECT q.id FROM webhook_deliveries q JOIN webhooks w ON w.id = q.webhook_id
+			WHERE q.status = 'pending' AND w.active AND q.next_attempt_at <= now()
+				AND (q.locked_until IS NULL OR q.locked_until <= now())
+			ORDER BY q.next_attempt_at, q.id
+			LIMIT $1
+			FOR UPDATE OF q SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		limit, lease.Milliseconds(),
+	)
+}
+
+func (s *Storage) CompleteWebhookDelivery(ctx context.Context, id int64, attempt storage.WebhookAttempt) (_ bool, err error) {
+	const op = "storage.postgre.CompleteWebhookDelivery"
+	ctx, end := s.begin(ctx, op)
+	defer end(&err)
+	var disabled bool
+	err = s.inTx(ctx, op, func(tx *sql.Tx) error {
+		var webhookID int64
+		var err error
+		if attempt.Error == "" {
+			err = tx.QueryRowContext(ctx,
+				`UPDATE webhook_deliveries SET status = 'succeeded', attempts = attempts + 1, response_status = $2,
+					last_error = NULL, delivered_at = now(), locked_until = NULL
+				WHERE id = $1 RETURNING webhook_id`,
+				id, attempt.ResponseStatus,
+			).Scan(&webhookID)
+		} else {
+			// нулевое NextAttemptAt — попытки исчерпаны
+			var next *time.Time
+			if !attempt.NextAttemptAt.IsZero() {
+				next = &attempt.NextAttemptAt
+			}
+			err = tx.QueryRowContext(ctx,
+				`UPDATE webhook_deliveries SET attempts = attempts + 1, response_status = NULLIF($2, 0), last_error = $3,
+					status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+					next_attempt_at = COALESCE($4, next_attempt_at), locked_until = NULL
+				WHERE id = $1 RETURNING webhook_id`,
+				id, attempt.ResponseStatus, attempt.Error, next,
+			).Scan(&webhookID)
+		}
+		if err == sql.ErrNoRows {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update webhook delivery", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+
+		if attempt.Error == "" {
+			_, err = tx.ExecContext(ctx, "UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1", webhookID)
+			return classify(err)
+		}
+		// счётчик неудач общий для всех доставок подписки: выключаем, только пока она ещё включена.
+		// now() — время начала транзакции, поэтому disabled_at совпадает с ним, только если выключили здесь
+		err = tx.QueryRowContext(ctx,
+			`UPDATE webhooks SET consecutive_failures = consecutive_failures + 1,
+				active = active AND NOT ($2 > 0 AND consecutive_failures + 1 >= $2),
+				disabled_at = CASE WHEN active AND $2 > 0 AND consecutive_failures + 1 >= $2 THEN now() ELSE disabled_at END
+			WHERE id = $1
+			RETURNING disabled_at IS NOT NULL AND disabled_at = now()`,
+			webhookID, attempt.MaxFailures,
+		).Scan(&disabled)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to count webhook failure", slog.String("op", op), slog.Any("error", err))
+			return classify(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return disabled, nil
+}
+
+// queryDeliveries выполняет запрос, возвращающий deliveryColumns.
+func (s *Storage) queryDeliveries(ctx context.Context, op, query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to query webhook deliveries", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.log.ErrorContext(ctx, "Failed to close rows", slog.String("op", op), slog.Any("error", cerr))
+		}
+	}()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to scan webhook delivery", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, classify(err))
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.ErrorContext(ctx, "Error iterating rows", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	return deliveries, nil
+}
+
+// webhookColumns — колонки подписки для SELECT ... FROM webhooks w; порядок совпадает со scanWebhook.
+const webhookColumns = `w.id, w.url, w.event_types, w.secret, w.active, w.consecutive_failures, w.disabled_at, w.created_at`
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.EventTypes), &webhook.Secret, &webhook.Active,
+		&webhook.ConsecutiveFailures, &webhook.DisabledAt, &webhook.CreatedAt)
+	return webhook, err
+}
+
+// deliveryColumns — колонки доставки для SELECT ... FROM webhook_deliveries d; порядок совпадает со scanDelivery.
+const deliveryColumns = `d.id, d.webhook_id, d.message_id, d.event_type, d.payload, d.status, d.attempts,
+	COALESCE(d.response_status, 0), COALESCE(d.last_error, ''),
+	CASE WHEN d.status = 'pending' THEN d.next_attempt_at END, d.created_at, d.delivered_at`
+
+func scanDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.MessageID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+	return d, err
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"TRYREST/internal/models"
+)
+
+// Ошибки хранилища. Бэкенды оборачивают их через %w, поэтому проверять нужно через errors.Is.
+var (
+	// ErrNotFound — запись с указанным id отсутствует.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict — нарушено ограничение уникальности (например, email уже занят).
+	ErrConflict = errors.New("conflict")
+	// ErrForeignKey — запись ссылается на несуществующую (бронирование на удалённое событие и т.п.).
+	ErrForeignKey = errors.New("referenced record does not exist")
+	// ErrEventFull — на событии не осталось свободных мест.
+	ErrEventFull = errors.New("event is full")
+	// ErrEventStarted — событие уже началось или прошло, бронировать его нельзя.
+	ErrEventStarted = errors.New("event has already started")
+	// ErrEventNotFull — на событии есть свободные места, вставать в очередь не нужно.
+	ErrEventNotFull = errors.New("event has free seats")
+	// ErrHoldExpired — срок удержания места истёк.
+	ErrHoldExpired = errors.New("hold expired")
+	// ErrInvalidStatus — операция не разрешена в текущем статусе бронирования
+	// (например, отмена уже отменённого или перенос посещённого).
+	ErrInvalidStatus = errors.New("operation not allowed in current booking status")
+	// ErrVersionMismatch — запись изменилась с тех пор, как клиент её прочитал (не совпал If-Match).
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrUnavailable — хранилище недоступно: нет соединения, БД перезапускается и т.п.
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrRetryable — транзакция прервана из-за взаимоблокировки или конфликта сериализации;
+	// повтор запроса, скорее всего, пройдёт.
+	ErrRetryable = errors.New("transaction aborted, retry")
+)
+
+// Версии записей (оптимистичная блокировка). Update* и Delete* пользователей, событий и бронирований
+// принимают ожидаемую версию: 0 — без проверки, иначе при несовпадении возвращается ErrVersionMismatch.
+// Любое изменение записи увеличивает её версию.
+
+// UserRepository — операции над пользователями.
+type UserRepository interface {
+	GetAllUsers(ctx context.Context, filter UserFilter) ([]models.User, error)
+	GetUserByID(ctx context.Context, id int64) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	// AddUser создаёт пользователя; PasswordHash может быть пустым.
+	AddUser(ctx context.Context, user models.User) (int64, error)
+	// UpdateUser обновляет имя и email пользователя с id == user.ID,
+	// а также роль, если user.Role не пустая. user.Version — ожидаемая версия.
+	UpdateUser(ctx context.Context, user models.User) error
+	// PatchUser меняет только заданные поля пользователя id (ошибки те же, что у UpdateUser).
+	PatchUser(ctx context.Context, id, version int64, patch UserPatch) error
+	DeleteUser(ctx context.Context, id, version int64) error
+}
+
+// EventRepository — операции над событиями.
+type EventRepository interface {
+	GetAllEvents(ctx context.Context, filter EventFilter) ([]models.Event, error)
+	GetEventByID(ctx context.Context, id int64) (models.Event, error)
+	// AddEvent создаёт событие; OrganizerID — создавший его пользователь.
+	AddEvent(ctx context.Context, event models.Event) (int64, error)
+	// UpdateEvent обновляет событие с id == event.ID, организатор не меняется. Возвращает ErrConflict,
+	// если новая вместимость меньше числа уже сделанных бронирований. Добавленные места
+	// отдаются листу ожидания. event.Version — ожидаемая версия.
+	UpdateEvent(ctx context.Context, event models.Event) error
+	// PatchEvent меняет только заданные поля события id; вместимость проверяется так же, как в UpdateEvent.
+	PatchEvent(ctx context.Context, id, version int64, patch EventPatch) error
+	DeleteEvent(ctx context.Context, id, version int64) error
+}
+
+// BookingRepository — операции над бронированиями.
+type BookingRepository interface {
+	GetAllBookings(ctx context.Context, filter BookingFilter) ([]models.Booking, error)
+	GetBookingByID(ctx context.Context, id int64) (models.Booking, error)
+	// AddBooking создаёт бронирование в статусе pending. Атомарно проверяет вместимость события
+	// (с учётом действующих холдов) и возвращает ErrEventFull, если мест нет,
+	// и ErrEventStarted, если событие уже началось.
+	AddBooking(ctx context.Context, eventID, userID int64) (int64, error)
+	// UpdateBooking переносит бронирование; для отменённых и посещённых возвращает ErrInvalidStatus.
+	// Освободившееся место на прежнем событии отдаётся первому в листе ожидания.
+	UpdateBooking(ctx context.Context, id, eventID, userID, version int64) error
+	// PatchBooking меняет только заданные поля бронирования id по правилам UpdateBooking.
+	PatchBooking(ctx context.Context, id, version int64, patch BookingPatch) error
+	// SetBookingStatus переводит бронирование в статус status и проставляет время перехода.
+	// Возвращает ErrInvalidStatus, если переход не разрешён (см. models.Booking.CanTransitionTo).
+	// При отмене место в той же транзакции отдаётся первому в листе ожидания.
+	SetBookingStatus(ctx context.Context, id int64, status string) (models.Booking, error)
+	// DeleteBooking удаляет бронирование; освободившееся место отдаётся первому в листе ожидания.
+	DeleteBooking(ctx context.Context, id, version int64) error
+}
+
+// WaitlistRepository — очередь на распроданные события. Когда место освобождается
+// (отмена, удаление или перенос бронирования, увеличение вместимости), первый в очереди
+// получает бронирование в статусе pending в той же транзакции, а хранилище публикует bus.WaitlistPromoted.
+type WaitlistRepository interface {
+	// JoinWaitlist ставит пользователя в конец очереди. Возвращает ErrNotFound, если события нет,
+	// ErrEventStarted, ErrEventNotFull, если места ещё есть, и ErrConflict, если пользователь
+	// уже в очереди или у него есть действующее бронирование.
+	JoinWaitlist(ctx context.Context, eventID, userID int64) (models.WaitlistEntry, error)
+	// GetWaitlistEntry возвращает место пользователя в очереди или ErrNotFound.
+	GetWaitlistEntry(ctx context.Context, eventID, userID int64) (models.WaitlistEntry, error)
+	LeaveWaitlist(ctx context.Context, eventID, userID int64) error
+}
+
+// RoleRepository — роли и их права.
+type RoleRepository interface {
+	// GetRolePermissions возвращает права каждой роли: роль -> список прав.
+	GetRolePermissions(ctx context.Context) (map[string][]string, error)
+}
+
+// HoldRepository — временное удержание мест. Действующий холд занимает место так же, как бронирование.
+type HoldRepository interface {
+	// AddHold удерживает место за пользователем на ttl. Возвращает ErrNotFound, если события нет,
+	// ErrEventStarted, ErrEventFull и ErrConflict, если у пользователя уже есть действующий холд на это событие.
+	AddHold(ctx context.Context, eventID, userID int64, ttl time.Duration) (models.Hold, error)
+	GetHoldByID(ctx context.Context, id int64) (models.Hold, error)
+	// ConfirmHold превращает холд в подтверждённое бронирование и удаляет его.
+	// Возвращает ErrHoldExpired, если срок истёк.
+	ConfirmHold(ctx context.Context, id int64) (models.Booking, error)
+	// ReleaseHold удаляет холд; место отдаётся листу ожидания.
+	ReleaseHold(ctx context.Context, id int64) error
+	// ReleaseExpiredHolds удаляет просроченные холды, отдаёт места листу ожидания
+	// и возвращает число удалённых.
+	ReleaseExpiredHolds(ctx context.Context) (int, error)
+}
+
+// IdempotencyRepository — ответы на запросы с Idempotency-Key.
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey атомарно занимает ключ rec.Key пользователя rec.UserID. Если ключа нет
+	// или он истёк, сохраняет rec без ответа и возвращает (rec, true); иначе — существующую запись и false.
+	// Остальные истёкшие ключи удаляет PurgeExpiredIdempotencyKeys.
+	ReserveIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey сохраняет ответ на запрос, занявший ключ.
+	CompleteIdempotencyKey(ctx context.Context, userID int64, key string, statusCode int, header http.Header, body []byte) error
+	// ReleaseIdempotencyKey удаляет ключ, чтобы запрос можно было повторить (после 5xx или паники).
+	ReleaseIdempotencyKey(ctx context.Context, userID int64, key string) error
+	// PurgeExpiredIdempotencyKeys удаляет ключи всех пользователей с истёкшим сроком и возвращает их число.
+	PurgeExpiredIdempotencyKeys(ctx context.Context) (int, error)
+}
+
+// OutboxRepository — доменные события (models.OutboxMessage), которые хранилище пишет в одной транзакции
+// с каждым изменением пользователя, события или бронирования. Доставляет их outbox.Dispatcher.
+type OutboxRepository interface {
+	// ClaimOutbox берёт в аренду на lease до limit сообщений, готовых к отправке: у каждого агрегата —
+	// только самое раннее недоставленное, чтобы получатели видели изменения агрегата по порядку.
+	// Сообщения в аренде у другого диспетчера пропускаются; по истечении аренды их можно взять снова.
+	// Сообщения, помеченные FailOutbox, не выдаются и не задерживают свой агрегат.
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	// AckOutbox удаляет доставленное сообщение.
+	AckOutbox(ctx context.Context, id int64) error
+	// RetryOutbox снимает аренду, увеличивает счётчик попыток и откладывает следующую до next.
+	RetryOutbox(ctx context.Context, id int64, next time.Time, reason string) error
+	// FailOutbox снимает аренду и помечает сообщение недоставляемым после последней попытки.
+	// Сообщение остаётся в outbox для разбора, но больше не выдаётся.
+	FailOutbox(ctx context.Context, id int64, reason string) error
+}
+
+// NotificationRepository — отметки о письмах, поставленных в очередь по сообщению outbox. Отметка живёт,
+// пока сообщение в outbox, поэтому повтор сообщения после сбоя или перезапуска не отправляет письма дважды.
+type NotificationRepository interface {
+	// ReserveNotification ставит отметку сообщению msgID; false — отметка уже стоит.
+	// Сообщения нет в outbox (уже доставлено) — ErrForeignKey.
+	ReserveNotification(ctx context.Context, msgID int64) (bool, error)
+	// ReleaseNotification снимает отметку, если поставить письма в очередь не удалось.
+	ReleaseNotification(ctx context.Context, msgID int64) error
+}
+
+// WebhookRepository — подписки на вебхуки и их доставки. Доставки создаются из сообщений outbox
+// и отправляются webhooks.Worker.
+type WebhookRepository interface {
+	AddWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetAllWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhookByID(ctx context.Context, id int64) (models.Webhook, error)
+	// EnableWebhook включает подписку и сбрасывает счётчик неудач; отложенные доставки уходят сразу.
+	EnableWebhook(ctx context.Context, id int64) (models.Webhook, error)
+	// DeleteWebhook удаляет подписку вместе с её доставками.
+	DeleteWebhook(ctx context.Context, id int64) error
+	// EnqueueWebhookDeliveries создаёт доставку msg с телом payload каждой активной подписке на msg.Type
+	// и возвращает их число. Повтор того же msg.ID новых доставок не создаёт.
+	EnqueueWebhookDeliveries(ctx context.Context, msg models.OutboxMessage, payload []byte) (int, error)
+	GetWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	// ClaimWebhookDeliveries берёт в аренду на lease до limit доставок в статусе pending, чья попытка
+	// уже наступила, — только у активных подписок.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	// CompleteWebhookDelivery записывает итог попытки (см. WebhookAttempt) и обновляет счётчик неудач
+	// подписки. Возвращает true, если подписка на этой попытке выключена.
+	CompleteWebhookDelivery(ctx context.Context, id int64, attempt WebhookAttempt) (bool, error)
+}
+
+// ReminderRepository — напоминания о событиях. Создаются и переносятся из сообщений outbox,
+// отправляются reminders.Scheduler.
+type ReminderRepository interface {
+	// ScheduleReminders пересчитывает срок ожидающих напоминаний бронирования по текущему началу его события
+	// и создаёт недостающие для offsets, срок которых ещё не прошёл. Для отменённого или посещённого
+	// бронирования новых не создаёт. Возвращает число созданных; повторный вызов ничего не дублирует.
+	ScheduleReminders(ctx context.Context, bookingID int64, offsets []time.Duration) (int, error)
+	// RescheduleEventReminders делает то же, что ScheduleReminders, для всех бронирований события.
+	RescheduleEventReminders(ctx context.Context, eventID int64, offsets []time.Duration) (int, error)
+	// CancelReminders отменяет ожидающие напоминания бронирования и возвращает их число.
+	CancelReminders(ctx context.Context, bookingID int64) (int, error)
+	// ClaimReminders берёт в аренду на lease до limit наступивших напоминаний в статусе pending.
+	// Напоминания отменённых и посещённых бронирований, начавшихся событий, а также вытесненные более
+	// поздним наступившим напоминанием того же бронирования отменяются и не возвращаются.
+	// Напоминания в аренде у другого инстанса пропускаются.
+	ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]models.Reminder, error)
+	// CompleteReminder помечает напоминание отправленным. ErrNotFound — его нет или оно уже не pending.
+	CompleteReminder(ctx context.Context, id int64) error
+}
+
+// WebhookAttempt — итог одной попытки доставки вебхука.
+type WebhookAttempt struct {
+	ResponseStatus int    // 0 — ответа не было
+	Error          string // пусто — доставлено
+	// NextAttemptAt — когда повторить неудачную попытку; нулевое — попытки исчерпаны, доставка failed
+	NextAttemptAt time.Time
+	// MaxFailures — после стольких неудачных попыток подряд подписка выключается; 0 — никогда
+	MaxFailures int
+}
+
+// Storage — всё, что нужно приложению от хранилища. Реализуется postgre.Storage и memory.Storage.
+type Storage interface {
+	UserRepository
+	EventRepository
+	BookingRepository
+	WaitlistRepository
+	HoldRepository
+	IdempotencyRepository
+	OutboxRepository
+	NotificationRepository
+	WebhookRepository
+	ReminderRepository
+	RoleRepository
+	// Ping проверяет, что хранилище доступно (для /readyz).
+	Ping(ctx context.Context) error
+	Close() error
+}
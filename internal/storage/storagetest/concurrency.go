@@ -0,0 +1,144 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"TRYREST/internal/storage"
+)
+
+// testConcurrentBookings: n участников одновременно бронируют событие на capacity мест —
+// ровно capacity успешных бронирований, остальные получают ErrEventFull и встают в очередь
+// с позициями 1..n-capacity без пропусков и повторов.
+func testConcurrentBookings(t *testing.T, s storage.Storage) {
+	const n, capacity = 300, 5
+	ctx := context.Background()
+	eventID := addEvent(t, s, capacity)
+	users := make([]int64, n)
+	for i := range users {
+		users[i] = addUser(t, s)
+	}
+
+	start := make(chan struct{})
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, userID := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start // все запросы стартуют вместе
+			_, errs[i] = s.AddBooking(ctx, eventID, userID)
+			if errors.Is(errs[i], storage.ErrEventFull) {
+				if _, err := s.JoinWaitlist(ctx, eventID, userID); err != nil {
+					t.Errorf("JoinWaitlist: %v", err)
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	booked, full := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, storage.ErrEventFull):
+			full++
+		default:
+			t.Errorf("AddBooking: unexpected error %v", err)
+		}
+	}
+	if booked != capacity || full != n-capacity {
+		t.Errorf("booked = %d, full = %d, want %d and %d", booked, full, capacity, n-capacity)
+	}
+
+	bookings, err := s.GetAllBookings(ctx, storage.BookingFilter{EventID: eventID})
+	if err != nil {
+		t.Fatalf("GetAllBookings: %v", err)
+	}
+	if len(bookings) != capacity {
+		t.Errorf("stored bookings = %d, want %d", len(bookings), capacity)
+	}
+	event, err := s.GetEventByID(ctx, eventID)
+	if err != nil {
+		t.Fatalf("GetEventByID: %v", err)
+	}
+	if event.RemainingSeats != 0 {
+		t.Errorf("remaining seats = %d, want 0", event.RemainingSeats)
+	}
+
+	positions := make(map[int]bool)
+	for i, userID := range users {
+		entry, err := s.GetWaitlistEntry(ctx, eventID, userID)
+		switch {
+		case errs[i] == nil:
+			wantErr(t, "GetWaitlistEntry of a booked user", err, storage.ErrNotFound)
+		case err != nil:
+			t.Errorf("GetWaitlistEntry: %v", err)
+		case entry.Position < 1 || entry.Position > n-capacity || positions[entry.Position]:
+			t.Errorf("waitlist position = %d, want a unique one in 1..%d", entry.Position, n-capacity)
+		default:
+			positions[entry.Position] = true
+		}
+	}
+	if len(positions) != n-capacity {
+		t.Errorf("waitlist entries = %d, want %d", len(positions), n-capacity)
+	}
+}
+
+// testOppositeMoves: бронирования одновременно переносятся между двумя событиями навстречу друг другу
+// через move (UpdateBooking или PatchBooking). Транзакции берут оба события, и без общего порядка
+// блокировок встречные переносы ждали бы друг друга; все переносы должны пройти.
+func testOppositeMoves(t *testing.T, s storage.Storage, move func(ctx context.Context, id, userID, to int64) error) {
+	const n = 50
+	ctx := context.Background()
+	// мест хватает на все бронирования сразу: проверяем блокировки, а не вместимость
+	first, second := addEvent(t, s, 2*n), addEvent(t, s, 2*n)
+
+	type planned struct{ id, userID, to int64 }
+	moves := make([]planned, 0, 2*n)
+	for i := range 2 * n {
+		from, to := first, second
+		if i%2 == 1 {
+			from, to = second, first
+		}
+		userID := addUser(t, s)
+		id, err := s.AddBooking(ctx, from, userID)
+		if err != nil {
+			t.Fatalf("AddBooking: %v", err)
+		}
+		moves = append(moves, planned{id: id, userID: userID, to: to})
+	}
+
+	start := make(chan struct{})
+	errs := make([]error, len(moves))
+	var wg sync.WaitGroup
+	for i, m := range moves {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = move(ctx, m.id, m.userID, m.to)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("move: %v", err)
+		}
+	}
+	for _, eventID := range []int64{first, second} {
+		bookings, err := s.GetAllBookings(ctx, storage.BookingFilter{EventID: eventID})
+		if err != nil {
+			t.Fatalf("GetAllBookings: %v", err)
+		}
+		if len(bookings) != n {
+			t.Errorf("event %d: bookings = %d, want %d", eventID, len(bookings), n)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// testCapacity: бронирования не превышают вместимость, отмена освобождает место,
+// вместимость нельзя опустить ниже числа бронирований, начавшееся событие не бронируется.
+func testCapacity(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	eventID := addEvent(t, s, 2)
+	first, err := s.AddBooking(ctx, eventID, addUser(t, s))
+	wantErr(t, "AddBooking", err, nil)
+	_, err = s.AddBooking(ctx, eventID, addUser(t, s))
+	wantErr(t, "AddBooking", err, nil)
+	_, err = s.AddBooking(ctx, eventID, addUser(t, s))
+	wantErr(t, "AddBooking on a full event", err, storage.ErrEventFull)
+
+	event, err := s.GetEventByID(ctx, eventID)
+	wantErr(t, "GetEventByID", err, nil)
+	event.Capacity = 1
+	wantErr(t, "UpdateEvent below booked seats", s.UpdateEvent(ctx, event), storage.ErrConflict)
+
+	_, err = s.SetBookingStatus(ctx, first, models.BookingCancelled)
+	wantErr(t, "SetBookingStatus", err, nil)
+	_, err = s.AddBooking(ctx, eventID, addUser(t, s))
+	wantErr(t, "AddBooking after cancellation", err, nil)
+
+	started := addEventAt(t, s, 10, time.Now().Add(-time.Hour))
+	_, err = s.AddBooking(ctx, started, addUser(t, s))
+	wantErr(t, "AddBooking on a started event", err, storage.ErrEventStarted)
+}
+
+// testWaitlistPromotion: очередь открыта только на распроданное событие, освободившееся место
+// получает первый в очереди, добавленные места — следующие.
+func testWaitlistPromotion(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	eventID := addEvent(t, s, 1)
+	holder, first, second := addUser(t, s), addUser(t, s), addUser(t, s)
+
+	_, err := s.JoinWaitlist(ctx, eventID, first)
+	wantErr(t, "JoinWaitlist with free seats", err, storage.ErrEventNotFull)
+	bookingID, err := s.AddBooking(ctx, eventID, holder)
+	wantErr(t, "AddBooking", err, nil)
+	_, err = s.JoinWaitlist(ctx, eventID, holder)
+	wantErr(t, "JoinWaitlist with a booking", err, storage.ErrConflict)
+
+	entry, err := s.JoinWaitlist(ctx, eventID, first)
+	wantErr(t, "JoinWaitlist", err, nil)
+	if entry.Position != 1 {
+		t.Errorf("first position = %d, want 1", entry.Position)
+	}
+	entry, err = s.JoinWaitlist(ctx, eventID, second)
+	wantErr(t, "JoinWaitlist", err, nil)
+	if entry.Position != 2 {
+		t.Errorf("second position = %d, want 2", entry.Position)
+	}
+	_, err = s.JoinWaitlist(ctx, eventID, second)
+	wantErr(t, "JoinWaitlist twice", err, storage.ErrConflict)
+
+	_, err = s.SetBookingStatus(ctx, bookingID, models.BookingCancelled)
+	wantErr(t, "SetBookingStatus", err, nil)
+	promoted := userBooking(t, s, eventID, first)
+	if promoted == nil || promoted.Status != models.BookingPending {
+		t.Fatalf("first in line booking = %+v, want pending", promoted)
+	}
+	_, err = s.GetWaitlistEntry(ctx, eventID, first)
+	wantErr(t, "GetWaitlistEntry of the promoted user", err, storage.ErrNotFound)
+	entry, err = s.GetWaitlistEntry(ctx, eventID, second)
+	wantErr(t, "GetWaitlistEntry", err, nil)
+	if entry.Position != 1 {
+		t.Errorf("second position after promotion = %d, want 1", entry.Position)
+	}
+
+	event, err := s.GetEventByID(ctx, eventID)
+	wantErr(t, "GetEventByID", err, nil)
+	event.Capacity = 2
+	wantErr(t, "UpdateEvent", s.UpdateEvent(ctx, event), nil)
+	if b := userBooking(t, s, eventID, second); b == nil || b.Status != models.BookingPending {
+		t.Fatalf("booking after capacity increase = %+v, want pending", b)
+	}
+	_, err = s.GetWaitlistEntry(ctx, eventID, second)
+	wantErr(t, "GetWaitlistEntry after capacity increase", err, storage.ErrNotFound)
+}
+
+// testHolds: холд занимает место, подтверждается в бронирование, при снятии отдаёт место очереди,
+// а просроченный холд не подтверждается и места не занимает.
+func testHolds(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	eventID := addEvent(t, s, 1)
+	user, other := addUser(t, s), addUser(t, s)
+
+	hold, err := s.AddHold(ctx, eventID, user, time.Minute)
+	wantErr(t, "AddHold", err, nil)
+	_, err = s.AddBooking(ctx, eventID, other)
+	wantErr(t, "AddBooking over a hold", err, storage.ErrEventFull)
+	booking, err := s.ConfirmHold(ctx, hold.ID)
+	wantErr(t, "ConfirmHold", err, nil)
+	if booking.UserID != user || booking.Status != models.BookingConfirmed {
+		t.Errorf("confirmed booking = %+v, want confirmed for user %d", booking, user)
+	}
+	_, err = s.GetHoldByID(ctx, hold.ID)
+	wantErr(t, "GetHoldByID after confirmation", err, storage.ErrNotFound)
+
+	// снятый холд отдаёт место первому в очереди
+	eventID = addEvent(t, s, 1)
+	hold, err = s.AddHold(ctx, eventID, user, time.Minute)
+	wantErr(t, "AddHold", err, nil)
+	_, err = s.AddHold(ctx, eventID, user, time.Minute)
+	wantErr(t, "AddHold on a full event", err, storage.ErrEventFull)
+	_, err = s.JoinWaitlist(ctx, eventID, other)
+	wantErr(t, "JoinWaitlist", err, nil)
+	wantErr(t, "ReleaseHold", s.ReleaseHold(ctx, hold.ID), nil)
+	if b := userBooking(t, s, eventID, other); b == nil || b.Status != models.BookingPending {
+		t.Fatalf("booking after ReleaseHold = %+v, want pending", b)
+	}
+
+	// повторный холд того же пользователя
+	eventID = addEvent(t, s, 2)
+	_, err = s.AddHold(ctx, eventID, user, time.Minute)
+	wantErr(t, "AddHold", err, nil)
+	_, err = s.AddHold(ctx, eventID, user, time.Minute)
+	wantErr(t, "AddHold twice", err, storage.ErrConflict)
+
+	// просроченный холд
+	eventID = addEvent(t, s, 1)
+	hold, err = s.AddHold(ctx, eventID, user, 10*time.Millisecond)
+	wantErr(t, "AddHold", err, nil)
+	time.Sleep(50 * time.Millisecond)
+	_, err = s.ConfirmHold(ctx, hold.ID)
+	wantErr(t, "ConfirmHold after expiry", err, storage.ErrHoldExpired)
+	_, err = s.AddBooking(ctx, eventID, other)
+	wantErr(t, "AddBooking over an expired hold", err, nil)
+	released, err := s.ReleaseExpiredHolds(ctx)
+	wantErr(t, "ReleaseExpiredHolds", err, nil)
+	if released != 1 {
+		t.Errorf("released = %d, want 1", released)
+	}
+}
+
+// testVersionConflicts: изменение с устаревшей версией отклоняется, с текущей — проходит и увеличивает её,
+// версия 0 не проверяется.
+func testVersionConflicts(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	eventID := addEvent(t, s, 5)
+	event, err := s.GetEventByID(ctx, eventID)
+	wantErr(t, "GetEventByID", err, nil)
+	stale := event.Version
+
+	event.Title = "Renamed"
+	wantErr(t, "UpdateEvent", s.UpdateEvent(ctx, event), nil)
+	event.Title = "Stale"
+	wantErr(t, "UpdateEvent with a stale version", s.UpdateEvent(ctx, event), storage.ErrVersionMismatch)
+	title := "Patched"
+	wantErr(t, "PatchEvent with a stale version",
+		s.PatchEvent(ctx, eventID, stale, storage.EventPatch{Title: &title}), storage.ErrVersionMismatch)
+	wantErr(t, "DeleteEvent with a stale version", s.DeleteEvent(ctx, eventID, stale), storage.ErrVersionMismatch)
+
+	current, err := s.GetEventByID(ctx, eventID)
+	wantErr(t, "GetEventByID", err, nil)
+	if current.Version <= stale || current.Title != "Renamed" {
+		t.Errorf("event after update = version %d, title %q; want version > %d, title %q",
+			current.Version, current.Title, stale, "Renamed")
+	}
+	wantErr(t, "PatchEvent without a version", s.PatchEvent(ctx, eventID, 0, storage.EventPatch{Title: &title}), nil)
+
+	userID := addUser(t, s)
+	bookingID, err := s.AddBooking(ctx, eventID, userID)
+	wantErr(t, "AddBooking", err, nil)
+	booking, err := s.GetBookingByID(ctx, bookingID)
+	wantErr(t, "GetBookingByID", err, nil)
+	_, err = s.SetBookingStatus(ctx, bookingID, models.BookingConfirmed)
+	wantErr(t, "SetBookingStatus", err, nil)
+	wantErr(t, "DeleteBooking with a stale version",
+		s.DeleteBooking(ctx, bookingID, booking.Version), storage.ErrVersionMismatch)
+	wantErr(t, "UpdateBooking with a stale version",
+		s.UpdateBooking(ctx, bookingID, addEvent(t, s, 5), userID, booking.Version), storage.ErrVersionMismatch)
+
+	user, err := s.GetUserByID(ctx, userID)
+	wantErr(t, "GetUserByID", err, nil)
+	user.Name = "Renamed"
+	wantErr(t, "UpdateUser", s.UpdateUser(ctx, user), nil)
+	wantErr(t, "UpdateUser with a stale version", s.UpdateUser(ctx, user), storage.ErrVersionMismatch)
+}
+
+// testDeleteUser: удаление пользователя освобождает его места и холды, их получает очередь,
+// а сам удалённый из очередей исчезает.
+func testDeleteUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	booked, held := addEvent(t, s, 1), addEvent(t, s, 1)
+	leaving, first, second := addUser(t, s), addUser(t, s), addUser(t, s)
+
+	_, err := s.AddBooking(ctx, booked, leaving)
+	wantErr(t, "AddBooking", err, nil)
+	_, err = s.AddHold(ctx, held, leaving, time.Hour)
+	wantErr(t, "AddHold", err, nil)
+	_, err = s.JoinWaitlist(ctx, booked, first)
+	wantErr(t, "JoinWaitlist", err, nil)
+	_, err = s.JoinWaitlist(ctx, held, second)
+	wantErr(t, "JoinWaitlist", err, nil)
+
+	user, err := s.GetUserByID(ctx, leaving)
+	wantErr(t, "GetUserByID", err, nil)
+	wantErr(t, "DeleteUser", s.DeleteUser(ctx, leaving, user.Version), nil)
+
+	if b := userBooking(t, s, booked, leaving); b != nil {
+		t.Errorf("deleted user booking = %+v, want none", b)
+	}
+	if b := userBooking(t, s, booked, first); b == nil || b.Status != models.BookingPending {
+		t.Errorf("booking freed by the deleted user = %+v, want pending", b)
+	}
+	if b := userBooking(t, s, held, second); b == nil || b.Status != models.BookingPending {
+		t.Errorf("hold freed by the deleted user = %+v, want a pending booking", b)
+	}
+}
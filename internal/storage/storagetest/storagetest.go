@@ -0,0 +1,103 @@
+// Package storagetest — общий набор тестов storage.Storage: одни и те же проверки выполняются
+// для memory и postgre, чтобы бэкенды не расходились в поведении.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Factory возвращает пустое хранилище для одного теста; закрывать его — через t.Cleanup.
+type Factory func(t *testing.T) storage.Storage
+
+// Run проверяет хранилище, созданное newStorage. Подтесты идут последовательно: у postgre
+// они делят одну базу.
+func Run(t *testing.T, newStorage Factory) {
+	t.Run("Capacity", func(t *testing.T) { testCapacity(t, newStorage(t)) })
+	t.Run("WaitlistPromotion", func(t *testing.T) { testWaitlistPromotion(t, newStorage(t)) })
+	t.Run("Holds", func(t *testing.T) { testHolds(t, newStorage(t)) })
+	t.Run("VersionConflicts", func(t *testing.T) { testVersionConflicts(t, newStorage(t)) })
+	t.Run("DeleteUser", func(t *testing.T) { testDeleteUser(t, newStorage(t)) })
+	t.Run("ConcurrentBookings", func(t *testing.T) { testConcurrentBookings(t, newStorage(t)) })
+	t.Run("OppositeMovesUpdate", func(t *testing.T) {
+		s := newStorage(t)
+		testOppositeMoves(t, s, func(ctx context.Context, id, userID, to int64) error {
+			return s.UpdateBooking(ctx, id, to, userID, 0)
+		})
+	})
+	t.Run("OppositeMovesPatch", func(t *testing.T) {
+		s := newStorage(t)
+		testOppositeMoves(t, s, func(ctx context.Context, id, _, to int64) error {
+			return s.PatchBooking(ctx, id, 0, storage.BookingPatch{EventID: &to})
+		})
+	})
+}
+
+// emails делает адреса пользователей уникальными в пределах процесса.
+var emails atomic.Int64
+
+// addUser создаёт участника.
+func addUser(t *testing.T, s storage.Storage) int64 {
+	t.Helper()
+	n := emails.Add(1)
+	id, err := s.AddUser(context.Background(), models.User{
+		Name:  fmt.Sprintf("User %d", n),
+		Email: fmt.Sprintf("user%d@example.com", n),
+		Role:  models.RoleAttendee,
+	})
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	return id
+}
+
+// addEvent создаёт событие на capacity мест, которое начнётся через сутки.
+func addEvent(t *testing.T, s storage.Storage, capacity int) int64 {
+	t.Helper()
+	return addEventAt(t, s, capacity, time.Now().Add(24*time.Hour))
+}
+
+// addEventAt создаёт двухчасовое событие на capacity мест с началом в start.
+func addEventAt(t *testing.T, s storage.Storage, capacity int, start time.Time) int64 {
+	t.Helper()
+	start = start.Truncate(time.Second)
+	id, err := s.AddEvent(context.Background(), models.Event{
+		Title:    "Event",
+		StartAt:  start,
+		EndAt:    start.Add(2 * time.Hour),
+		TimeZone: "UTC",
+		Capacity: capacity,
+	})
+	if err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	return id
+}
+
+// userBooking возвращает бронирование пользователя на событии или nil, если его нет.
+func userBooking(t *testing.T, s storage.Storage, eventID, userID int64) *models.Booking {
+	t.Helper()
+	bookings, err := s.GetAllBookings(context.Background(), storage.BookingFilter{EventID: eventID, UserID: userID})
+	if err != nil {
+		t.Fatalf("GetAllBookings: %v", err)
+	}
+	if len(bookings) == 0 {
+		return nil
+	}
+	return &bookings[0]
+}
+
+// wantErr проверяет, что err оборачивает want (nil — ошибки быть не должно).
+func wantErr(t *testing.T, call string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("%s: err = %v, want %v", call, err, want)
+	}
+}
@@ -0,0 +1,376 @@
+// Package stream — живые обновления событий для Server-Sent Events: свободные места и изменения события.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Типы SSE-сообщений (поле event).
+const (
+	// TypeAvailability — вместимость, свободные места или статус изменились (data — Availability).
+	TypeAvailability = "availability"
+	// TypeEvent — изменились поля события (data — models.Event).
+	TypeEvent = "event"
+	// TypeDeleted — событие удалено (data — {"event_id": N}); поток после него закрывается.
+	TypeDeleted = "deleted"
+)
+
+// Статусы события в Availability.
+const (
+	StatusAvailable = "available"
+	StatusSoldOut   = "sold_out"
+	StatusStarted   = "started"
+)
+
+var (
+	// ErrClosed — хаб остановлен, сервер завершает работу.
+	ErrClosed = errors.New("stream hub is closed")
+	// ErrTooManySubscribers — достигнут stream.max_subscribers.
+	ErrTooManySubscribers = errors.New("too many stream subscribers")
+)
+
+// Availability — данные сообщения TypeAvailability.
+type Availability struct {
+	EventID        int64  `json:"event_id"`
+	Capacity       int    `json:"capacity"`
+	RemainingSeats int    `json:"remaining_seats"`
+	Status         string `json:"status"`
+}
+
+func availabilityOf(event models.Event, now time.Time) Availability {
+	a := Availability{EventID: event.ID, Capacity: event.Capacity, RemainingSeats: event.RemainingSeats, Status: StatusAvailable}
+	switch {
+	case !event.StartAt.After(now):
+		a.Status = StatusStarted
+	case event.RemainingSeats <= 0:
+		a.Status = StatusSoldOut
+	}
+	return a
+}
+
+// Message — одно SSE-сообщение.
+type Message struct {
+	ID   string // "<эпоха хаба>-<номер>", см. Hub.Subscribe
+	Type string
+	Data []byte
+}
+
+// Subscriber — подписка одного клиента. C закрывается, когда хаб останавливается, событие удалено
+// или клиент не успевает читать; после этого клиент переподключается с Last-Event-ID.
+type Subscriber struct {
+	C       <-chan Message
+	c       chan Message
+	eventID int64
+}
+
+// topic — подписчики одного события, его последнее известное состояние и хвост сообщений для возобновления.
+type topic struct {
+	refresh     sync.Mutex // упорядочивает загрузку и сравнение состояния
+	subscribers map[*Subscriber]struct{}
+	event       models.Event
+	available   Availability
+	seq         int64     // номер последнего сообщения темы или, пока их нет, номер её создания
+	trimmed     int64     // номер последнего сообщения, вытесненного из хвоста, или номер создания темы
+	history     []Message // последние stream.history сообщений по возрастанию номера
+}
+
+// Hub раздаёт изменения событий подписчикам. Изменения приходят как сообщения outbox (Hub — outbox.Sink)
+// и раз в stream.resync_interval перечитываются из хранилища: так видны холды, начало события
+// и изменения, которые доставил outbox другого инстанса.
+type Hub struct {
+	storage storage.EventRepository
+	cfg     config.Stream
+	log     *slog.Logger
+	// epoch отличает номера сообщений разных запусков: после рестарта старый Last-Event-ID не совпадёт
+	epoch string
+
+	mu     sync.Mutex
+	topics map[int64]*topic
+	// seq — общий счётчик номеров сообщений: тема, созданная заново после ухода всех подписчиков,
+	// продолжает нумерацию, и старые Last-Event-ID не совпадают с новыми
+	seq    int64
+	count  int
+	closed bool
+
+	runner loop.Runner
+}
+
+func NewHub(storage storage.EventRepository, cfg config.Stream, log *slog.Logger) *Hub {
+	return &Hub{
+		storage: storage,
+		cfg:     cfg,
+		log:     log.With(slog.String("component", "stream")),
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		topics:  make(map[int64]*topic),
+	}
+}
+
+// Subscribe подписывает клиента на событие event (только что прочитанное из хранилища) и возвращает
+// сообщения, которые нужно отправить сразу: пропущенные после lastEventID, если хаб их ещё помнит,
+// иначе — текущую доступность.
+func (h *Hub) Subscribe(event models.Event, lastEventID string) (*Subscriber, []Message, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, ErrClosed
+	}
+	if h.cfg.MaxSubscribers > 0 && h.count >= h.cfg.MaxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	t, ok := h.topics[event.ID]
+	if !ok {
+		// пока темы не было, изменения никто не записывал: свой номер создания отправит
+		// переподключившимся текущее состояние вместо пустого хвоста
+		h.seq++
+		t = &topic{
+			subscribers: make(map[*Subscriber]struct{}),
+			event:       event,
+			available:   availabilityOf(event, time.Now()),
+			seq:         h.seq,
+			trimmed:     h.seq,
+		}
+		h.topics[event.ID] = t
+	}
+	c := make(chan Message, h.cfg.Buffer)
+	sub := &Subscriber{C: c, c: c, eventID: event.ID}
+	t.subscribers[sub] = struct{}{}
+	h.count++
+
+	if missed, ok := h.since(t, lastEventID); ok {
+		return sub, missed, nil
+	}
+	// номер текущего состояния: при переподключении с ним клиент получит только новые сообщения
+	snapshot := Message{ID: h.messageID(t.seq), Type: TypeAvailability, Data: mustMarshal(t.available)}
+	return sub, []Message{snapshot}, nil
+}
+
+// since возвращает сообщения после lastEventID или false, если хаб такого номера не помнит. Вызывать под h.mu.
+func (h *Hub) since(t *topic, lastEventID string) ([]Message, bool) {
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != h.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseInt(seqStr, 10, 64)
+	// номера до trimmed — из вытесненной части хвоста или из прошлой жизни темы
+	if err != nil || seq > t.seq || seq < t.trimmed {
+		return nil, false
+	}
+	if seq == t.seq {
+		return nil, true // клиент ничего не пропустил
+	}
+	var missed []Message
+	for _, msg := range t.history {
+		if h.seqOf(msg) > seq {
+			missed = append(missed, msg)
+		}
+	}
+	return missed, true
+}
+
+func (h *Hub) seqOf(msg Message) int64 {
+	_, seqStr, _ := strings.Cut(msg.ID, "-")
+	seq, _ := strconv.ParseInt(seqStr, 10, 64)
+	return seq
+}
+
+func (h *Hub) messageID(seq int64) string {
+	return h.epoch + "-" + strconv.FormatInt(seq, 10)
+}
+
+// Unsubscribe отписывает клиента; последнего подписчика события хаб забывает вместе с хвостом сообщений.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.topics[sub.eventID]
+	if !ok {
+		return
+	}
+	if _, ok := t.subscribers[sub]; !ok {
+		return // уже отключён хабом
+	}
+	h.drop(t, sub)
+	if len(t.subscribers) == 0 {
+		delete(h.topics, sub.eventID)
+	}
+}
+
+// drop закрывает канал подписчика. Вызывать под h.mu.
+func (h *Hub) drop(t *topic, sub *Subscriber) {
+	delete(t.subscribers, sub)
+	close(sub.c)
+	h.count--
+}
+
+// Send — outbox.Sink: перечитывает событие, которого касается сообщение, если на него кто-то подписан.
+// Ошибок не возвращает, чтобы живые обновления не задерживали остальных получателей outbox:
+// пропущенное изменение подтянет resync.
+func (h *Hub) Send(ctx context.Context, msg models.OutboxMessage) error {
+	var eventID int64
+	switch msg.AggregateType {
+	case bus.AggregateEvent:
+		eventID = msg.AggregateID
+	case bus.AggregateBooking:
+		var booking models.Booking
+		if err := json.Unmarshal(msg.Payload, &booking); err != nil {
+			h.log.Error("failed to decode booking message", slog.Int64("id", msg.ID), sl.Err(err))
+			return nil
+		}
+		eventID = booking.EventID
+	default:
+		return nil
+	}
+	if msg.Type == bus.EventDeleted {
+		h.deleted(eventID)
+		return nil
+	}
+	h.refresh(ctx, eventID)
+	return nil
+}
+
+// refresh перечитывает событие и рассылает подписчикам то, что изменилось.
+func (h *Hub) refresh(ctx context.Context, eventID int64) {
+	h.mu.Lock()
+	t, ok := h.topics[eventID]
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	t.refresh.Lock()
+	defer t.refresh.Unlock()
+
+	event, err := h.storage.GetEventByID(ctx, eventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.deleted(eventID)
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to refresh event", slog.Int64("event_id", eventID), sl.Err(err))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[eventID] != t {
+		return // подписчики ушли, пока читали
+	}
+	if event.Version != t.event.Version {
+		t.event = event
+		h.publish(t, TypeEvent, event)
+	}
+	if available := availabilityOf(event, time.Now()); available != t.available {
+		t.available = available
+		h.publish(t, TypeAvailability, available)
+	}
+}
+
+// deleted сообщает подписчикам об удалении события и закрывает их потоки.
+func (h *Hub) deleted(eventID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.topics[eventID]
+	if !ok {
+		return
+	}
+	h.publish(t, TypeDeleted, map[string]int64{"event_id": eventID})
+	for sub := range t.subscribers {
+		h.drop(t, sub)
+	}
+	delete(h.topics, eventID)
+}
+
+// publish добавляет сообщение в хвост и раздаёт подписчикам. Подписчик с полной очередью отключается:
+// ждать его нельзя, а пропуск он восполнит при переподключении. Вызывать под h.mu.
+func (h *Hub) publish(t *topic, msgType string, data any) {
+	h.seq++
+	t.seq = h.seq
+	msg := Message{ID: h.messageID(t.seq), Type: msgType, Data: mustMarshal(data)}
+	t.history = append(t.history, msg)
+	if over := len(t.history) - h.cfg.History; over > 0 {
+		t.trimmed = h.seqOf(t.history[over-1])
+		t.history = t.history[over:]
+	}
+	for sub := range t.subscribers {
+		select {
+		case sub.c <- msg:
+		default:
+			h.log.Warn("dropping slow stream subscriber", slog.Int64("event_id", sub.eventID))
+			h.drop(t, sub)
+		}
+	}
+}
+
+// Start запускает фоновую сверку с хранилищем. При resync_interval <= 0 обновления приходят только
+// из outbox этого инстанса.
+func (h *Hub) Start() {
+	if h.cfg.ResyncInterval <= 0 {
+		h.log.Warn("stream resync disabled", slog.Duration("interval", h.cfg.ResyncInterval))
+		return
+	}
+	h.runner.Start(h.cfg.ResyncInterval, h.resync)
+}
+
+// resync сверяет с хранилищем все события, у которых есть подписчики.
+func (h *Hub) resync(ctx context.Context) bool {
+	h.mu.Lock()
+	ids := make([]int64, 0, len(h.topics))
+	for id := range h.topics {
+		ids = append(ids, id)
+	}
+	h.mu.Unlock()
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		// начатое чтение не прерываем: Stop дожидается его
+		h.refresh(context.WithoutCancel(ctx), id)
+	}
+	return false
+}
+
+// Close закрывает все потоки и больше не принимает подписчиков. Регистрируется через
+// http.Server.RegisterOnShutdown: иначе Shutdown ждал бы бесконечные ответы до своего таймаута.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for id, t := range h.topics {
+		for sub := range t.subscribers {
+			h.drop(t, sub)
+		}
+		delete(h.topics, id)
+	}
+}
+
+// Stop останавливает сверку и ждёт её завершения, но не дольше ctx.
+func (h *Hub) Stop(ctx context.Context) error {
+	h.Close()
+	return h.runner.Stop(ctx)
+}
+
+// mustMarshal сериализует данные сообщений — структуры из этого пакета и models, ошибка невозможна.
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("marshal stream message: %v", err))
+	}
+	return data
+}
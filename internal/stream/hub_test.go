@@ -0,0 +1,222 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/config"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage/memory"
+)
+
+// hubFixture — Hub поверх memory-хранилища с одним событием; сверка не запущена,
+// изменения доставляются через Send, как это делает outbox.
+type hubFixture struct {
+	t       *testing.T
+	storage *memory.Storage
+	hub     *Hub
+	event   models.Event
+	users   int
+}
+
+func newHubFixture(t *testing.T, cfg config.Stream, capacity int) *hubFixture {
+	t.Helper()
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.New(log, bus.New())
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	id, err := s.AddEvent(ctx, models.Event{Title: "Go meetup", StartAt: start, EndAt: start.Add(2 * time.Hour), TimeZone: "UTC", Capacity: capacity})
+	if err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	event, err := s.GetEventByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetEventByID: %v", err)
+	}
+	hub := NewHub(s, cfg, log)
+	t.Cleanup(hub.Close)
+	return &hubFixture{t: t, storage: s, hub: hub, event: event}
+}
+
+// subscribe подписывается, как обработчик потока: с только что прочитанным событием.
+func (f *hubFixture) subscribe(lastEventID string) (*Subscriber, []Message) {
+	f.t.Helper()
+	event, err := f.storage.GetEventByID(context.Background(), f.event.ID)
+	if err != nil {
+		f.t.Fatalf("GetEventByID: %v", err)
+	}
+	sub, initial, err := f.hub.Subscribe(event, lastEventID)
+	if err != nil {
+		f.t.Fatalf("Subscribe: %v", err)
+	}
+	return sub, initial
+}
+
+// book занимает место новым участником и доставляет хабу сообщение outbox о событии.
+func (f *hubFixture) book() {
+	f.t.Helper()
+	ctx := context.Background()
+	f.users++
+	userID, err := f.storage.AddUser(ctx, models.User{Name: "Ann", Email: fmt.Sprintf("user%d@example.com", f.users), Role: models.RoleAttendee})
+	if err != nil {
+		f.t.Fatalf("AddUser: %v", err)
+	}
+	if _, err := f.storage.AddBooking(ctx, f.event.ID, userID); err != nil {
+		f.t.Fatalf("AddBooking: %v", err)
+	}
+	f.hub.Send(ctx, models.OutboxMessage{Type: bus.BookingCreated, AggregateType: bus.AggregateEvent, AggregateID: f.event.ID})
+}
+
+// remaining возвращает свободные места из сообщения TypeAvailability.
+func remaining(t *testing.T, msg Message) int {
+	t.Helper()
+	if msg.Type != TypeAvailability {
+		t.Fatalf("message type = %s, want %s", msg.Type, TypeAvailability)
+	}
+	var a Availability
+	if err := json.Unmarshal(msg.Data, &a); err != nil {
+		t.Fatalf("decode availability: %v", err)
+	}
+	return a.RemainingSeats
+}
+
+func ids(messages []Message) []string {
+	var ids []string
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestHubReplaysSinceLastEventID(t *testing.T) {
+	f := newHubFixture(t, config.Stream{Buffer: 16, History: 2}, 3)
+	watcher, initial := f.subscribe("") // держит тему открытой
+	if len(initial) != 1 || remaining(t, initial[0]) != 3 {
+		t.Fatalf("initial = %v, want a snapshot with 3 seats", initial)
+	}
+	created := initial[0].ID
+	var published []string
+	for range 3 {
+		f.book() // в хвосте остаются два последних сообщения
+		published = append(published, (<-watcher.C).ID)
+	}
+	last := published[2]
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []string // nil — ничего не отправлять
+		snapshot    bool     // вместо хвоста — текущее состояние с номером последнего сообщения
+	}{
+		{"first connection", "", nil, true},
+		{"up to date", last, nil, false},
+		{"missed one", published[1], published[2:], false},
+		{"missed two", published[0], published[1:], false},
+		{"evicted from history", created, nil, true},
+		{"other epoch", "0-2", nil, true},
+		{"from the future", f.hub.epoch + "-99", nil, true},
+		{"malformed", "garbage", nil, true},
+		{"malformed sequence", f.hub.epoch + "-x", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, got := f.subscribe(tt.lastEventID)
+			defer f.hub.Unsubscribe(sub)
+			if tt.snapshot {
+				if len(got) != 1 || got[0].ID != last || remaining(t, got[0]) != 0 {
+					t.Errorf("messages = %v, want a snapshot %s with no seats", ids(got), last)
+				}
+				return
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("messages = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+// TestHubRecreatedTopic: пока у события не было подписчиков, хаб его не отслеживал; клиент, вернувшийся
+// с номером из прошлой жизни темы, получает текущее состояние, а новые номера не повторяют старые.
+func TestHubRecreatedTopic(t *testing.T) {
+	f := newHubFixture(t, config.Stream{Buffer: 16, History: 16}, 3)
+	sub, initial := f.subscribe("")
+	f.hub.Unsubscribe(sub)
+	f.book() // тема удалена: сообщение никому не отправлено
+
+	again, got := f.subscribe(initial[0].ID)
+	if len(got) != 1 || got[0].ID == initial[0].ID || remaining(t, got[0]) != 2 {
+		t.Fatalf("messages = %v, want a new snapshot with 2 seats", ids(got))
+	}
+	f.book()
+	msg := <-again.C
+	if msg.ID == initial[0].ID || msg.ID == got[0].ID || remaining(t, msg) != 1 {
+		t.Errorf("message %s (%s), want a new number with 1 seat", msg.ID, msg.Data)
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	f := newHubFixture(t, config.Stream{Buffer: 1, History: 16}, 3)
+	slow, _ := f.subscribe("")
+	fast, _ := f.subscribe("")
+
+	var fastGot []Message
+	for range 2 {
+		f.book()
+		select {
+		case msg := <-fast.C:
+			fastGot = append(fastGot, msg)
+		default:
+			t.Fatal("fast subscriber got no message")
+		}
+	}
+	if len(fastGot) != 2 || remaining(t, fastGot[1]) != 1 {
+		t.Fatalf("fast subscriber got %v, want two updates", ids(fastGot))
+	}
+
+	// медленный получил первое сообщение в буфер, на втором отключён
+	first, ok := <-slow.C
+	if !ok || first.ID != fastGot[0].ID {
+		t.Fatalf("slow subscriber first message = %v, %v; want %s", first.ID, ok, fastGot[0].ID)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber channel is open, want it closed")
+	}
+	f.hub.Unsubscribe(slow) // отключённого хабом повторная отписка не трогает
+	f.book()
+	if msg, ok := <-fast.C; !ok || remaining(t, msg) != 0 {
+		t.Fatalf("fast subscriber after drop got %v, %v", msg.ID, ok)
+	}
+
+	// переподключение с Last-Event-ID досылает пропущенное
+	_, missed := f.subscribe(first.ID)
+	if len(missed) != 2 || missed[0].ID != fastGot[1].ID {
+		t.Errorf("replay after reconnect = %v, want from %s", ids(missed), fastGot[1].ID)
+	}
+}
+
+func TestHubClosesStreamsOfDeletedEvent(t *testing.T) {
+	f := newHubFixture(t, config.Stream{Buffer: 4, History: 4, MaxSubscribers: 1}, 3)
+	sub, _ := f.subscribe("")
+	if _, _, err := f.hub.Subscribe(f.event, ""); err != ErrTooManySubscribers {
+		t.Fatalf("Subscribe over the limit = %v, want %v", err, ErrTooManySubscribers)
+	}
+
+	f.hub.Send(context.Background(), models.OutboxMessage{Type: bus.EventDeleted, AggregateType: bus.AggregateEvent, AggregateID: f.event.ID})
+	msg, ok := <-sub.C
+	if !ok || msg.Type != TypeDeleted {
+		t.Fatalf("message = %+v, %v; want %s", msg, ok, TypeDeleted)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("channel is open after deletion, want it closed")
+	}
+	// место подписчика освободилось
+	other, _ := f.subscribe("")
+	f.hub.Unsubscribe(other)
+}
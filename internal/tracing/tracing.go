@@ -0,0 +1,67 @@
+// Package tracing — трассировка OpenTelemetry: провайдер с экспортёром из конфига, спаны HTTP-запросов
+// и trace_id в логах. Спаны запросов к БД открывает само хранилище (postgre).
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"TRYREST/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// Экспортёры спанов для config.Tracing.Exporter.
+const (
+	ExporterNone   = "none"   // спаны не пишутся, но traceparent из запросов попадает в логи
+	ExporterStdout = "stdout" // JSON по строке на спан — для локальной отладки
+	ExporterOTLP   = "otlp"   // OTLP/HTTP в коллектор (Jaeger, Tempo, otel-collector)
+)
+
+const serviceName = "booker"
+
+// Setup настраивает глобальный провайдер трассировки и W3C-пропагатор (traceparent, baggage).
+// Возвращаемая shutdown дописывает накопленные спаны; её нужно вызвать при остановке.
+func Setup(ctx context.Context, cfg config.Tracing) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES из окружения переопределяют значения по умолчанию
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// решение вызывающего сервиса (флаг sampled в traceparent) важнее собственной доли
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
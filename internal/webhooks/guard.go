@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenTarget — адрес подписки не публичный: loopback, частная сеть, link-local и т.п.
+// Иначе подписка позволила бы отправлять подписанные запросы во внутреннюю сеть сервиса.
+var ErrForbiddenTarget = errors.New("webhook target is not a public address")
+
+// reserved — диапазоны, которые не отсекают методы netip.Addr: «эта сеть», CGNAT и сеть для тестов производительности.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// checkAddr отклоняет адреса, которые не ведут в интернет.
+func checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap() // ::ffff:127.0.0.1 — тот же loopback
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%s: %w", addr, ErrForbiddenTarget)
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%s: %w", addr, ErrForbiddenTarget)
+		}
+	}
+	return nil
+}
+
+// CheckTarget проверяет при создании подписки, что хост rawURL — публичный адрес: IP как есть,
+// имя — все адреса, в которые оно разрешается. Ошибка DNS возвращается как есть.
+// DNS может позже начать отдавать другой адрес, поэтому Worker проверяет адрес ещё и при каждом соединении.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := checkAddr(addr); err != nil {
+			return fmt.Errorf("%s resolves to %w", host, err)
+		}
+	}
+	return nil
+}
+
+// dialControl — net.Dialer.Control: проверяет адрес, с которым уже разрешённое имя соединяется на самом деле.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %w", address, ErrForbiddenTarget)
+	}
+	return checkAddr(addrPort.Addr())
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"TRYREST/internal/config"
+)
+
+func TestCheckAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // метаданные облака
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := checkAddr(netip.MustParseAddr(tt.addr))
+			if tt.allowed && err != nil {
+				t.Errorf("checkAddr(%s) = %v, want nil", tt.addr, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbiddenTarget) {
+				t.Errorf("checkAddr(%s) = %v, want %v", tt.addr, err, ErrForbiddenTarget)
+			}
+		})
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"https://[2606:2800:220:1:248:1893:25c8:1946]:8443/hook", true},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://localhost/hook", false}, // имя разрешается в loopback
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := CheckTarget(context.Background(), tt.url)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckTarget = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbiddenTarget) {
+				t.Errorf("CheckTarget = %v, want %v", err, ErrForbiddenTarget)
+			}
+		})
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	if err := dialControl("tcp4", "127.0.0.1:443", nil); !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("dialControl(127.0.0.1) = %v, want %v", err, ErrForbiddenTarget)
+	}
+	if err := dialControl("tcp6", "[2606:2800:220:1:248:1893:25c8:1946]:443", nil); err != nil {
+		t.Errorf("dialControl(public) = %v, want nil", err)
+	}
+}
+
+func TestTransportRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := http.Client{Transport: newTransport(config.Webhooks{Timeout: time.Second})}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("GET %s = %v, want %v", srv.URL, err, ErrForbiddenTarget)
+	}
+
+	client = http.Client{Transport: newTransport(config.Webhooks{Timeout: time.Second, AllowPrivate: true})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET %s with allow_private = %v", srv.URL, err)
+	}
+	resp.Body.Close()
+}
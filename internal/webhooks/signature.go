@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Заголовки запроса вебхука.
+const (
+	HeaderEvent     = "X-Booker-Event"     // тип сообщения, например BookingCreated
+	HeaderDelivery  = "X-Booker-Delivery"  // id доставки; одинаков у повторов
+	HeaderTimestamp = "X-Booker-Timestamp" // Unix-время отправки в секундах
+	// HeaderSignature — "sha256=" и hex HMAC-SHA256 от "<timestamp>.<тело>" на секрете подписки.
+	// Получатель сверяет подпись и отклоняет запросы со старым timestamp, защищаясь от повтора
+	HeaderSignature = "X-Booker-Signature"
+)
+
+// Sign возвращает значение HeaderSignature для тела body, отправленного в момент timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись за постоянное время — для получателей и тестов.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Sink — получатель outbox: раскладывает сообщение по доставкам подписок на его тип.
+// Сама отправка — в Worker, поэтому медленный партнёр не задерживает outbox.
+type Sink struct {
+	storage storage.WebhookRepository
+}
+
+func NewSink(storage storage.WebhookRepository) *Sink {
+	return &Sink{storage: storage}
+}
+
+func (s *Sink) Send(ctx context.Context, msg models.OutboxMessage) error {
+	// тело запроса — сообщение целиком: партнёр получает id для отсева повторов и время события
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message %d: %w", msg.ID, err)
+	}
+	_, err = s.storage.EnqueueWebhookDeliveries(ctx, msg, payload)
+	return err
+}
@@ -0,0 +1,165 @@
+// Package webhooks — подписки партнёров на доменные события: подпись запросов, отправка с повторами
+// и выключение подписок, которые постоянно отвечают ошибкой.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"TRYREST/internal/config"
+	"TRYREST/internal/lib/logger/sl"
+	"TRYREST/internal/lib/loop"
+	"TRYREST/internal/lib/retry"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+)
+
+// Worker раз в poll_interval забирает наступившие доставки и отправляет их, не больше concurrency
+// одновременно. Неудачная попытка повторяется с экспоненциальной задержкой до max_attempts раз.
+type Worker struct {
+	storage storage.WebhookRepository
+	cfg     config.Webhooks
+	client  *http.Client
+	log     *slog.Logger
+	runner  loop.Runner
+}
+
+// NewWorker создаёт воркер. client == nil — http.Client с таймаутом webhooks.timeout, который
+// соединяется только с публичными адресами (см. CheckTarget), если не задан webhooks.allow_private;
+// свой клиент нужен, например, для httptest.Server с TLS, и адреса не проверяет. Редиректы воркер
+// не выполняет ни с каким клиентом, чтобы подписанный запрос не ушёл на чужой адрес.
+func NewWorker(storage storage.WebhookRepository, cfg config.Webhooks, client *http.Client, log *slog.Logger) *Worker {
+	noRedirect := http.Client{Timeout: cfg.Timeout, Transport: newTransport(cfg)}
+	if client != nil {
+		noRedirect = *client
+	}
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Worker{storage: storage, cfg: cfg, client: &noRedirect, log: log.With(slog.String("component", "webhooks"))}
+}
+
+// Start запускает фоновую горутину. Остановить её — Stop. При poll_interval <= 0 отправка выключена.
+func (w *Worker) Start() {
+	if w.cfg.PollInterval <= 0 {
+		w.log.Warn("webhook worker disabled", slog.Duration("interval", w.cfg.PollInterval))
+		return
+	}
+	w.runner.Start(w.cfg.PollInterval, w.poll)
+}
+
+// Stop останавливает горутину и ждёт текущие запросы, но не дольше ctx.
+// Забранные, но не отправленные доставки вернутся в очередь по истечении lease.
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+// newTransport — транспорт клиента по умолчанию. Прокси из окружения не используется: проверка адреса
+// при соединении увидела бы адрес прокси, а не подписки.
+func newTransport(cfg config.Webhooks) *http.Transport {
+	dialer := &net.Dialer{Timeout: cfg.Timeout, KeepAlive: 30 * time.Second}
+	if !cfg.AllowPrivate {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// poll отправляет одну пачку доставок и сообщает, была ли она полной.
+func (w *Worker) poll(ctx context.Context) bool {
+	// начатые запросы доводим до конца: Stop дожидается их
+	work := context.WithoutCancel(ctx)
+	deliveries, err := w.storage.ClaimWebhookDeliveries(work, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		w.log.Error("failed to claim webhook deliveries", sl.Err(err))
+		return false
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(w.cfg.Concurrency, 1))
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			w.deliver(work, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries) == w.cfg.BatchSize
+}
+
+// deliver выполняет одну попытку и записывает её итог.
+func (w *Worker) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	log := w.log.With(slog.Int64("delivery_id", delivery.ID), slog.Int64("webhook_id", delivery.WebhookID))
+	webhook, err := w.storage.GetWebhookByID(ctx, delivery.WebhookID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return // подписку удалили вместе с доставками
+	}
+	if err != nil {
+		log.Error("failed to load webhook", sl.Err(err))
+		return
+	}
+
+	status, err := w.post(ctx, webhook, delivery)
+	attempt := storage.WebhookAttempt{ResponseStatus: status, MaxFailures: w.cfg.MaxFailures}
+	if err != nil {
+		attempt.Error = err.Error()
+		if delivery.Attempts+1 < w.cfg.MaxAttempts {
+			attempt.NextAttemptAt = time.Now().Add(retry.Backoff(delivery.Attempts, w.cfg.MinBackoff, w.cfg.MaxBackoff))
+		}
+		log.Warn("webhook delivery failed", slog.Int("attempt", delivery.Attempts+1),
+			slog.Bool("will_retry", !attempt.NextAttemptAt.IsZero()), sl.Err(err))
+	}
+
+	disabled, err := w.storage.CompleteWebhookDelivery(ctx, delivery.ID, attempt)
+	if err != nil {
+		// доставка вернётся после lease и уйдёт повторно
+		log.Error("failed to record webhook delivery", sl.Err(err))
+		return
+	}
+	if disabled {
+		log.Warn("webhook disabled after consecutive failures", slog.Int("failures", w.cfg.MaxFailures))
+	}
+}
+
+// post отправляет подписанный запрос и возвращает код ответа (0 — ответа не было).
+// Успех — любой 2xx; ответ-редирект считается ошибкой.
+func (w *Worker) post(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "booker-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// дочитываем немного, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,180 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"TRYREST/internal/bus"
+	"TRYREST/internal/config"
+	"TRYREST/internal/models"
+	"TRYREST/internal/storage"
+	"TRYREST/internal/storage/memory"
+)
+
+// receiver — подписчик на httptest.Server: запоминает запросы и отвечает кодами из statuses по очереди,
+// а когда они закончатся — 200.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("read body: %v", err)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// workerFixture — Worker поверх memory-хранилища с одной подпиской на receiver и одной доставкой.
+type workerFixture struct {
+	t        *testing.T
+	storage  *memory.Storage
+	receiver *receiver
+	worker   *Worker
+	webhook  models.Webhook
+	payload  []byte
+}
+
+func newWorkerFixture(t *testing.T, cfg config.Webhooks, statuses ...int) *workerFixture {
+	t.Helper()
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rc := &receiver{t: t, secret: "s3cret", statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	s := memory.New(log, bus.New())
+	webhook, err := s.AddWebhook(ctx, models.Webhook{URL: srv.URL, EventTypes: []string{bus.BookingCreated}, Secret: rc.secret})
+	if err != nil {
+		t.Fatalf("AddWebhook: %v", err)
+	}
+	payload := []byte(`{"id":1,"type":"BookingCreated"}`)
+	msg := models.OutboxMessage{ID: 1, Type: bus.BookingCreated}
+	if n, err := s.EnqueueWebhookDeliveries(ctx, msg, payload); err != nil || n != 1 {
+		t.Fatalf("EnqueueWebhookDeliveries = %d, %v; want 1, nil", n, err)
+	}
+	// свой клиент: сервер слушает loopback, который клиент по умолчанию не пропускает
+	worker := NewWorker(s, cfg, srv.Client(), log)
+	return &workerFixture{t: t, storage: s, receiver: rc, worker: worker, webhook: webhook, payload: payload}
+}
+
+// delivery возвращает единственную доставку подписки.
+func (f *workerFixture) delivery() models.WebhookDelivery {
+	f.t.Helper()
+	deliveries, err := f.storage.GetWebhookDeliveries(context.Background(), storage.WebhookDeliveryFilter{WebhookID: f.webhook.ID})
+	if err != nil {
+		f.t.Fatalf("GetWebhookDeliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		f.t.Fatalf("deliveries = %d, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func testWorkerConfig() config.Webhooks {
+	// нулевая задержка: повтор можно забрать следующим же poll
+	return config.Webhooks{BatchSize: 10, Concurrency: 2, Timeout: 5 * time.Second, Lease: time.Minute, MaxAttempts: 5}
+}
+
+func TestWorkerRetriesServerErrors(t *testing.T) {
+	f := newWorkerFixture(t, testWorkerConfig(), http.StatusInternalServerError, http.StatusBadGateway)
+	ctx := context.Background()
+
+	for i, want := range []struct {
+		status   string
+		response int
+	}{
+		{models.DeliveryPending, http.StatusInternalServerError},
+		{models.DeliveryPending, http.StatusBadGateway},
+		{models.DeliverySucceeded, http.StatusOK},
+	} {
+		f.worker.poll(ctx)
+		d := f.delivery()
+		if d.Status != want.status || d.Attempts != i+1 || d.ResponseStatus != want.response {
+			t.Fatalf("after poll %d: status %s, attempts %d, response %d; want %s, %d, %d",
+				i+1, d.Status, d.Attempts, d.ResponseStatus, want.status, i+1, want.response)
+		}
+		if want.status == models.DeliveryPending && (d.LastError == "" || d.NextAttemptAt == nil) {
+			t.Errorf("after poll %d: last error %q, next attempt %v; want both set", i+1, d.LastError, d.NextAttemptAt)
+		}
+	}
+	d := f.delivery()
+	if d.LastError != "" || d.NextAttemptAt != nil || d.DeliveredAt == nil {
+		t.Errorf("delivered: last error %q, next attempt %v, delivered at %v", d.LastError, d.NextAttemptAt, d.DeliveredAt)
+	}
+
+	// доставленное больше не отправляется
+	f.worker.poll(ctx)
+	if len(f.receiver.requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(f.receiver.requests))
+	}
+	for i, r := range f.receiver.requests {
+		body := f.receiver.bodies[i]
+		if string(body) != string(f.payload) {
+			t.Errorf("request %d: body %s, want %s", i+1, body, f.payload)
+		}
+		if got := r.Header.Get(HeaderEvent); got != bus.BookingCreated {
+			t.Errorf("request %d: %s = %q, want %q", i+1, HeaderEvent, got, bus.BookingCreated)
+		}
+		if got := r.Header.Get(HeaderDelivery); got != strconv.FormatInt(d.ID, 10) {
+			t.Errorf("request %d: %s = %q, want %d", i+1, HeaderDelivery, got, d.ID)
+		}
+		timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if err != nil || time.Since(time.Unix(timestamp, 0)).Abs() > time.Minute {
+			t.Errorf("request %d: %s = %q, want the current Unix time", i+1, HeaderTimestamp, r.Header.Get(HeaderTimestamp))
+		}
+		// подпись — HMAC от "<timestamp>.<тело>" на секрете подписки
+		if !Verify(f.receiver.secret, timestamp, body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("request %d: signature %q does not match", i+1, r.Header.Get(HeaderSignature))
+		}
+		if Verify("wrong", timestamp, body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("request %d: signature matches a wrong secret", i+1)
+		}
+	}
+}
+
+func TestWorkerGivesUpAfterMaxAttempts(t *testing.T) {
+	cfg := testWorkerConfig()
+	cfg.MaxAttempts, cfg.MaxFailures = 2, 2
+	f := newWorkerFixture(t, cfg, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	ctx := context.Background()
+
+	for range 3 {
+		f.worker.poll(ctx)
+	}
+	if len(f.receiver.requests) != 2 {
+		t.Errorf("requests = %d, want 2", len(f.receiver.requests))
+	}
+	d := f.delivery()
+	if d.Status != models.DeliveryFailed || d.Attempts != 2 || d.NextAttemptAt != nil {
+		t.Errorf("delivery: status %s, attempts %d, next attempt %v; want %s, 2, nil",
+			d.Status, d.Attempts, d.NextAttemptAt, models.DeliveryFailed)
+	}
+	webhook, err := f.storage.GetWebhookByID(ctx, f.webhook.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookByID: %v", err)
+	}
+	if webhook.Active || webhook.ConsecutiveFailures != 2 {
+		t.Errorf("webhook: active %v, failures %d; want disabled after 2", webhook.Active, webhook.ConsecutiveFailures)
+	}
+}
@@ -0,0 +1,15 @@
+-- ответы на POST-запросы с заголовком Idempotency-Key; status_code NULL — запрос ещё выполняется
+CREATE TABLE idempotency_keys
+(
+    user_id      BIGINT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key          VARCHAR(255) NOT NULL,
+    fingerprint  CHAR(64)     NOT NULL,
+    status_code  INTEGER,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body         BYTEA,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,6 @@
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS version;
+ALTER TABLE events
+    DROP COLUMN IF EXISTS version;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS version;
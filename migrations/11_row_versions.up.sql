@@ -0,0 +1,8 @@
+-- версия строки для оптимистичной блокировки (ETag / If-Match); растёт при каждом UPDATE.
+-- waitlist, holds и idempotency_keys не меняются после вставки, версия им не нужна
+ALTER TABLE users
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE events
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE bookings
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,18 @@
+-- transactional outbox: доменные события пишутся в одной транзакции с изменением и доставляются диспетчером.
+-- Доставленные сообщения удаляются; locked_until — аренда сообщения диспетчером, пока он его отправляет
+CREATE TABLE outbox
+(
+    id              BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    type            VARCHAR(64) NOT NULL,
+    aggregate_type  VARCHAR(32) NOT NULL,
+    aggregate_id    BIGINT      NOT NULL,
+    payload         JSONB       NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMPTZ
+);
+
+-- порядок внутри агрегата: диспетчер берёт только самое раннее сообщение каждого агрегата
+CREATE INDEX outbox_aggregate_idx ON outbox (aggregate_type, aggregate_id, id);
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'webhooks:manage';
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
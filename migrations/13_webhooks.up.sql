@@ -0,0 +1,38 @@
+-- подписки партнёров на доменные события; secret — ключ HMAC-подписи запросов
+CREATE TABLE webhooks
+(
+    id                   BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    url                  VARCHAR(2048) NOT NULL,
+    event_types          TEXT[]        NOT NULL,
+    secret               VARCHAR(255)  NOT NULL,
+    active               BOOLEAN       NOT NULL DEFAULT TRUE,
+    -- неудачные попытки подряд по всем доставкам; на пороге подписка выключается
+    consecutive_failures INTEGER       NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+-- доставка одного сообщения outbox одной подписке; журнал попыток отдаётся через GET /webhooks/{id}/deliveries
+CREATE TABLE webhook_deliveries
+(
+    id              BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    message_id      BIGINT      NOT NULL,
+    event_type      VARCHAR(64) NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    -- outbox доставляет хотя бы один раз: повтор сообщения не создаёт вторую доставку
+    UNIQUE (webhook_id, message_id)
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('admin', 'webhooks:manage');
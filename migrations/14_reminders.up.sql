@@ -0,0 +1,17 @@
+-- напоминания участникам за offset_seconds до начала события; создаются из сообщений outbox
+-- и отправляются планировщиком, который забирает их через SKIP LOCKED
+CREATE TABLE reminders
+(
+    id             BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    booking_id     BIGINT      NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+    offset_seconds BIGINT      NOT NULL CHECK (offset_seconds > 0),
+    due_at         TIMESTAMPTZ NOT NULL,
+    status         VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'cancelled')),
+    locked_until   TIMESTAMPTZ,
+    sent_at        TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- outbox доставляет хотя бы один раз: повтор сообщения не создаёт второе напоминание
+    UNIQUE (booking_id, offset_seconds)
+);
+
+CREATE INDEX reminders_pending_idx ON reminders (due_at) WHERE status = 'pending';
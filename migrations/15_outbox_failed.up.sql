@@ -0,0 +1,3 @@
+-- failed_at — сообщение исчерпало outbox.max_attempts: диспетчер его больше не берёт, и оно не задерживает
+-- следующие сообщения своего агрегата. Строка остаётся вместе с last_error для разбора
+ALTER TABLE outbox ADD COLUMN failed_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- отметки о письмах по сообщениям outbox: повтор сообщения после сбоя другого получателя или перезапуска
+-- не ставит письма заново. Отметка удаляется вместе с доставленным сообщением
+CREATE TABLE outbox_notifications
+(
+    outbox_id  BIGINT      NOT NULL PRIMARY KEY REFERENCES outbox (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
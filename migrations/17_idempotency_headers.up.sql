@@ -0,0 +1,5 @@
+-- сохранённый ответ повторяется со всеми заголовками (Location, ETag, ...), а не только с Content-Type
+ALTER TABLE idempotency_keys ADD COLUMN headers JSONB NOT NULL DEFAULT '{}';
+UPDATE idempotency_keys SET headers = jsonb_build_object('Content-Type', jsonb_build_array(content_type))
+WHERE content_type <> '';
+ALTER TABLE idempotency_keys DROP COLUMN content_type;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS bookings_event_id_idx;
+ALTER TABLE events
+    DROP COLUMN IF EXISTS capacity;
@@ -0,0 +1,7 @@
+-- существующим событиям выставляем вместимость по умолчанию, новые обязаны указывать её явно
+ALTER TABLE events
+    ADD COLUMN capacity INTEGER NOT NULL DEFAULT 100 CHECK (capacity > 0);
+ALTER TABLE events
+    ALTER COLUMN capacity DROP DEFAULT;
+
+CREATE INDEX bookings_event_id_idx ON bookings (event_id);
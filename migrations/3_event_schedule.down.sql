@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS events_start_at_idx;
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_end_after_start,
+    DROP COLUMN IF EXISTS time_zone,
+    DROP COLUMN IF EXISTS end_at,
+    DROP COLUMN IF EXISTS start_at;
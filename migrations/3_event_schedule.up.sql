@@ -0,0 +1,11 @@
+-- существующим событиям ставим расписание "сейчас + 1 час", новые обязаны указывать его явно
+ALTER TABLE events
+    ADD COLUMN start_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN end_at    TIMESTAMPTZ NOT NULL DEFAULT now() + INTERVAL '1 hour',
+    ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD CONSTRAINT events_end_after_start CHECK (end_at > start_at);
+ALTER TABLE events
+    ALTER COLUMN start_at DROP DEFAULT,
+    ALTER COLUMN end_at DROP DEFAULT;
+
+CREATE INDEX events_start_at_idx ON events (start_at);
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS events_start_at_idx ON events (start_at);
+
+DROP INDEX IF EXISTS bookings_user_id_idx;
+DROP INDEX IF EXISTS events_title_id_idx;
+DROP INDEX IF EXISTS events_start_at_id_idx;
+DROP INDEX IF EXISTS users_name_id_idx;
@@ -0,0 +1,7 @@
+-- индексы под keyset-пагинацию (поле сортировки, id) и фильтры списков
+CREATE INDEX users_name_id_idx ON users (name, id);
+CREATE INDEX events_start_at_id_idx ON events (start_at, id);
+CREATE INDEX events_title_id_idx ON events (title, id);
+CREATE INDEX bookings_user_id_idx ON bookings (user_id);
+
+DROP INDEX IF EXISTS events_start_at_idx;
@@ -0,0 +1,3 @@
+-- bcrypt-хеш пароля; NULL у пользователей, созданных через POST /users — они не могут войти
+ALTER TABLE users
+    ADD COLUMN password_hash VARCHAR(255);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS events_organizer_id_idx;
+ALTER TABLE events
+    DROP COLUMN IF EXISTS organizer_id;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,37 @@
+-- роли и их права; набор прав роли читается приложением при старте
+CREATE TABLE roles
+(
+    name        VARCHAR(32) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions
+(
+    role       VARCHAR(32) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description)
+VALUES ('admin', 'Управляет пользователями и любыми событиями и бронированиями'),
+       ('organizer', 'Создаёт и редактирует свои события'),
+       ('attendee', 'Бронирует места на события');
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('admin', 'users:manage'),
+       ('admin', 'events:write'),
+       ('admin', 'events:write_any'),
+       ('admin', 'bookings:write'),
+       ('admin', 'bookings:write_any'),
+       ('organizer', 'events:write'),
+       ('organizer', 'bookings:write'),
+       ('attendee', 'bookings:write');
+
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'attendee' REFERENCES roles (name);
+
+-- у событий, созданных до появления ролей, организатора нет — их редактируют только админы
+ALTER TABLE events
+    ADD COLUMN organizer_id BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX events_organizer_id_idx ON events (organizer_id);
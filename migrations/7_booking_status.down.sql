@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS bookings_event_id_active_idx;
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS attended_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS confirmed_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,13 @@
+-- существующие бронирования считаем подтверждёнными, новые создаются в статусе pending
+ALTER TABLE bookings
+    ADD COLUMN status       VARCHAR(16) NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('pending', 'confirmed', 'cancelled', 'attended')),
+    ADD COLUMN created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN confirmed_at TIMESTAMPTZ,
+    ADD COLUMN cancelled_at TIMESTAMPTZ,
+    ADD COLUMN attended_at  TIMESTAMPTZ;
+ALTER TABLE bookings
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+-- места занимают все бронирования, кроме отменённых
+CREATE INDEX bookings_event_id_active_idx ON bookings (event_id) WHERE status <> 'cancelled';
@@ -0,0 +1,12 @@
+-- очередь на распроданные события; запись удаляется, когда пользователь получает бронирование
+CREATE TABLE waitlist
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id   BIGINT      NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (event_id, user_id)
+);
+
+-- порядок очереди — по id внутри события
+CREATE INDEX waitlist_event_id_id_idx ON waitlist (event_id, id);
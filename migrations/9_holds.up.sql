@@ -0,0 +1,14 @@
+-- временное удержание места на время оформления; действующие холды занимают места наравне с бронированиями
+CREATE TABLE holds
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id   BIGINT      NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    CHECK (expires_at > created_at)
+);
+
+CREATE INDEX holds_event_id_expires_at_idx ON holds (event_id, expires_at);
+-- для фонового удаления просроченных
+CREATE INDEX holds_expires_at_idx ON holds (expires_at);
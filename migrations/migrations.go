@@ -0,0 +1,8 @@
+// Package migrations встраивает SQL-миграции в бинарник. Файлы именуются по правилам golang-migrate:
+// N_name.up.sql и N_name.down.sql; применяет их internal/migrator.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS